@@ -0,0 +1,332 @@
+package ro
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// ChangeOp is a kind of changes recorded in the change feed.
+type ChangeOp string
+
+// Kinds of changes.
+const (
+	ChangePut    ChangeOp = "put"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// ChangeEvent is a change of a model recorded in the change feed.
+// ID is a position of the event in the stream, and watching from it resumes after the event.
+// ScoreSetKeys are keys of score sets which the model was added to or removed from.
+// Model is decoded from a payload, which is recorded only with WithChangeFeedPayload on Put and Update.
+// Models of update events contain only updated fields unless the store has a codec.
+// Err is set on the last event of a channel when reading the change feed fails.
+type ChangeEvent[T any] struct {
+	ID           string
+	Op           ChangeOp
+	Suffix       string
+	ScoreSetKeys []string
+	Model        T
+	HasModel     bool
+	Err          error
+}
+
+const (
+	// watchBlockTimeout is a timeout of XREAD blocking to wait for changes, so that Watch can notice cancellations.
+	watchBlockTimeout = time.Second
+	// watchBatchSize is the maximum number of changes read at once.
+	watchBatchSize = 100
+)
+
+func (s *redisStore) getChangesKey() string {
+	return s.getStoreKey(s.ChangeFeedKeySuffix)
+}
+
+// sendChange appends a change of the model with the key to the change feed stream.
+func (s *redisStore) sendChange(conn Conn, op ChangeOp, key string, scoreSetKeys []string, payload []byte) error {
+	changesKey := s.getChangesKey()
+	args := redis.Args{}.Add(changesKey)
+	if s.ChangeFeedMaxLen > 0 {
+		args = args.Add("MAXLEN", "~", s.ChangeFeedMaxLen)
+	}
+	args = args.Add("*", "op", string(op), "key", strings.TrimPrefix(key, s.getKeyPrefix()+s.KeyDelimiter))
+	for _, k := range scoreSetKeys {
+		args = args.Add("score", k)
+	}
+	if payload != nil {
+		args = args.Add("payload", payload)
+	}
+
+	err := conn.Send("XADD", args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send XADD %s", changesKey)
+	}
+	return nil
+}
+
+// sendEntryChange appends a change of the entry, whose model was a member of current score sets.
+// Only the fields are recorded in the payload when they are given.
+func (s *redisStore) sendEntryChange(conn Conn, op ChangeOp, e *putEntry, fields []reflect.StructField, currentZsetKeys []string, version *versionField) error {
+	var payload []byte
+	if s.ChangeFeedPayloadEnabled {
+		var err error
+		payload, err = s.encodePayload(e.model, fields, version, e.version+1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(s.sendChange(conn, op, e.key, mergeScoreSetKeys(e.getScoreSetKeys(), currentZsetKeys), payload))
+}
+
+// encodePayload encodes the model with the codec, or hash fields of the model as a JSON object.
+// When fields are given, only hash fields of them are encoded.
+func (s *redisStore) encodePayload(m interface{}, fields []reflect.StructField, version *versionField, v int64) ([]byte, error) {
+	if s.Codec != nil {
+		return s.encode(m, v)
+	}
+
+	var pairs []interface{}
+	if fields == nil {
+		var err error
+		pairs, _, err = toHash(m)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	for _, f := range fields {
+		p, _, err := toHashFields(m, f.Index)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pairs = append(pairs, p...)
+	}
+
+	values := make(map[string]string, len(pairs)/2+1)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		values[pairs[i].(string)] = string(pairs[i+1].([]byte))
+	}
+	if version != nil {
+		values[version.hashField] = strconv.FormatInt(v, 10)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode a payload")
+	}
+	return data, nil
+}
+
+// decodePayload decodes a payload encoded by encodePayload into a new model.
+func (s *redisStore) decodePayload(data []byte) (reflect.Value, error) {
+	if s.Codec != nil {
+		v, err := s.decode(data)
+		if err != nil {
+			return reflect.Value{}, errors.WithStack(err)
+		}
+		return v.Addr(), nil
+	}
+
+	var fields map[string]string
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return reflect.Value{}, errors.Wrap(err, "failed to decode a payload")
+	}
+	pairs := make([]interface{}, 0, 2*len(fields))
+	for k, v := range fields {
+		pairs = append(pairs, k, v)
+	}
+	v := reflect.New(s.modelType)
+	err = scanHash(pairs, v.Interface())
+	if err != nil {
+		return reflect.Value{}, errors.Wrapf(err, "failed to scan a payload %q", data)
+	}
+	return v, nil
+}
+
+// mergeScoreSetKeys returns sorted score set keys of either of new and current memberships.
+func mergeScoreSetKeys(keys, currentKeys []string) []string {
+	seen := make(map[string]struct{}, len(keys)+len(currentKeys))
+	merged := make([]string, 0, len(keys)+len(currentKeys))
+	for _, k := range append(append([]string{}, keys...), currentKeys...) {
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		merged = append(merged, k)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// Watch implements the types.Store interface.
+func (s *redisStore) Watch(ctx context.Context, fromID string) (<-chan *ChangeEvent[interface{}], error) {
+	return watchChanges(ctx, s, fromID, true, func(v reflect.Value) interface{} { return v.Interface() })
+}
+
+// watchChanges reads changes after fromID, or changes made after the call when fromID is empty, and sends them to the returned channel.
+// Models of changes are converted from pointers to new models by conv.
+// Commands are reported to observers only when observed is true, since XREAD returns every watchBlockTimeout while no changes are made.
+// The channel is closed when ctx is done or reading changes fails.
+func watchChanges[T any](ctx context.Context, s *redisStore, fromID string, observed bool, conv func(reflect.Value) T) (<-chan *ChangeEvent[T], error) {
+	if !s.ChangeFeedEnabled {
+		return nil, errors.New("Watch requires the change feed")
+	}
+
+	op := &Operation{Name: "Watch"}
+	v, err := s.intercept(ctx, op, func(ctx context.Context, op *Operation) (interface{}, error) {
+		getConn := s.getConn
+		if !observed {
+			getConn = s.pool.GetContext
+		}
+		conn, err := getConn(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to acquire a connection")
+		}
+
+		lastID := fromID
+		if lastID == "" {
+			lastID, err = s.getLastChangeID(conn)
+			if err != nil {
+				conn.Close()
+				return nil, errors.WithStack(err)
+			}
+		}
+
+		ch := make(chan *ChangeEvent[T])
+		go func() {
+			defer close(ch)
+			defer conn.Close()
+			readChanges(ctx, s, conn, lastID, ch, conv)
+		}()
+		return ch, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(chan *ChangeEvent[T]), nil
+}
+
+// getLastChangeID returns an ID of the last change, or 0 when no changes are recorded.
+func (s *redisStore) getLastChangeID(conn Conn) (string, error) {
+	changesKey := s.getChangesKey()
+	entries, err := redis.Values(conn.Do("XREVRANGE", changesKey, "+", "-", "COUNT", 1))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to execute XREVRANGE %s", changesKey)
+	}
+	if len(entries) == 0 {
+		return "0", nil
+	}
+	entry, err := redis.Values(entries[0], nil)
+	if err != nil || len(entry) == 0 {
+		return "", errors.Errorf("unexpected XREVRANGE reply %v", entries)
+	}
+	id, err := redis.String(entry[0], nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "unexpected XREVRANGE reply %v", entries)
+	}
+	return id, nil
+}
+
+// readChanges sends changes after lastID to the channel until ctx is done or reading changes fails.
+func readChanges[T any](ctx context.Context, s *redisStore, conn Conn, lastID string, ch chan<- *ChangeEvent[T], conv func(reflect.Value) T) {
+	changesKey := s.getChangesKey()
+	fail := func(err error) {
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case ch <- &ChangeEvent[T]{Err: err}:
+		case <-ctx.Done():
+		}
+	}
+
+	for ctx.Err() == nil {
+		reply, err := conn.Do("XREAD", "COUNT", watchBatchSize, "BLOCK", watchBlockTimeout.Milliseconds(), "STREAMS", changesKey, lastID)
+		if err != nil {
+			fail(errors.Wrapf(err, "failed to execute XREAD %s %s", changesKey, lastID))
+			return
+		}
+		entries, err := getStreamEntries(reply)
+		if err != nil {
+			fail(errors.WithStack(err))
+			return
+		}
+
+		for _, entry := range entries {
+			e, err := parseChange(s, entry, conv)
+			if err != nil {
+				fail(errors.WithStack(err))
+				return
+			}
+			lastID = e.ID
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// getStreamEntries returns entries of the first stream in a reply of XREAD, or nil when XREAD timed out.
+func getStreamEntries(reply interface{}) ([]interface{}, error) {
+	if reply == nil {
+		return nil, nil
+	}
+	streams, err := redis.Values(reply, nil)
+	if err != nil || len(streams) == 0 {
+		return nil, errors.Errorf("unexpected XREAD reply %v", reply)
+	}
+	stream, err := redis.Values(streams[0], nil)
+	if err != nil || len(stream) != 2 {
+		return nil, errors.Errorf("unexpected XREAD reply %v", reply)
+	}
+	entries, err := redis.Values(stream[1], nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unexpected XREAD reply %v", reply)
+	}
+	return entries, nil
+}
+
+// parseChange parses an entry of the change feed stream, which is a pair of an ID and field-value pairs.
+func parseChange[T any](s *redisStore, entry interface{}, conv func(reflect.Value) T) (*ChangeEvent[T], error) {
+	values, err := redis.Values(entry, nil)
+	if err != nil || len(values) != 2 {
+		return nil, errors.Errorf("unexpected stream entry %v", entry)
+	}
+	id, err := redis.String(values[0], nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unexpected stream entry %v", entry)
+	}
+	fields, err := redis.Strings(values[1], nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unexpected stream entry %v", entry)
+	}
+
+	e := &ChangeEvent[T]{ID: id}
+	for i := 0; i+1 < len(fields); i += 2 {
+		switch value := fields[i+1]; fields[i] {
+		case "op":
+			e.Op = ChangeOp(value)
+		case "key":
+			e.Suffix = value
+		case "score":
+			e.ScoreSetKeys = append(e.ScoreSetKeys, value)
+		case "payload":
+			v, err := s.decodePayload([]byte(value))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decode a payload of %s", id)
+			}
+			e.Model, e.HasModel = conv(v), true
+		}
+	}
+	return e, nil
+}
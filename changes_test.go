@@ -0,0 +1,241 @@
+package ro_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func receiveChange[T any](t *testing.T, ch <-chan *ro.ChangeEvent[T]) *ro.ChangeEvent[T] {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("Watch() channel was closed unexpectedly")
+		}
+		if e.Err != nil {
+			t.Fatalf("Unexpected error: %v", e.Err)
+		}
+		return e
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for a change")
+	}
+	return nil
+}
+
+func TestRedisStore_Watch(t *testing.T) {
+	defer teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := ro.New(pool, &rotesting.Post{}, ro.WithChangeFeed(true))
+
+	err := store.Put(ctx, &rotesting.Post{ID: 1, Title: "post 1", UpdatedAt: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ch, err := store.Watch(ctx, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = store.Put(ctx, []*rotesting.Post{
+		{ID: 2, Title: "post 2", UpdatedAt: 20},
+		{ID: 3, Title: "post 3", UpdatedAt: 30},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = store.Delete(ctx, []*rotesting.Post{{ID: 2}, {ID: 4}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = store.DeleteAll(ctx, rq.Key("recent"), rq.Gt(20))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	scoreSetKeys := []string{"Post/id", "Post/recent"}
+	wants := []*ro.ChangeEvent[interface{}]{
+		{Op: ro.ChangePut, Suffix: "2", ScoreSetKeys: scoreSetKeys},
+		{Op: ro.ChangePut, Suffix: "3", ScoreSetKeys: scoreSetKeys},
+		{Op: ro.ChangeDelete, Suffix: "2", ScoreSetKeys: scoreSetKeys},
+		{Op: ro.ChangeDelete, Suffix: "3", ScoreSetKeys: scoreSetKeys},
+	}
+	var ids []string
+	for _, want := range wants {
+		got := receiveChange(t, ch)
+		if got.ID == "" {
+			t.Errorf("ChangeEvent.ID should be present")
+		}
+		ids = append(ids, got.ID)
+		got.ID = ""
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Watch() sent %+v, want %+v", got, want)
+		}
+	}
+
+	resumed, err := store.Watch(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range ids[2:] {
+		if got := receiveChange(t, resumed).ID; got != want {
+			t.Errorf("Watch() resumed from %s, want %s", got, want)
+		}
+	}
+
+	all, err := store.Watch(ctx, "0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := receiveChange(t, all).Suffix, "1"; got != want {
+		t.Errorf("Watch() from the beginning sent a change of %s, want %s", got, want)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestRedisStore_Watch_WhenDisabled(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = store.Watch(context.TODO(), "0")
+	if err == nil {
+		t.Error("Watch() should return an error without the change feed")
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	exists, err := redis.Bool(conn.Do("EXISTS", "Post#changes"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exists {
+		t.Error("Changes should not be recorded")
+	}
+}
+
+func TestTypedStore_Watch_WithPayload(t *testing.T) {
+	defer teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := ro.NewTyped[*TaggedPost](pool, ro.WithChangeFeed(true), ro.WithChangeFeedPayload(true))
+
+	ch, err := store.Watch(ctx, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	post := &TaggedPost{ID: 1, UserID: 2, Title: "post 1", CreatedAt: 100}
+	err = store.Put(ctx, post)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = store.Update(ctx, &TaggedPost{ID: 1, UserID: 3}, "UserID")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := receiveChange(t, ch)
+	if got, want := got.Op, ro.ChangePut; got != want {
+		t.Errorf("ChangeEvent.Op is %s, want %s", got, want)
+	}
+	if !got.HasModel || !reflect.DeepEqual(got.Model, post) {
+		t.Errorf("ChangeEvent.Model is %+v, want %+v", got.Model, post)
+	}
+
+	got = receiveChange(t, ch)
+	if got, want := got.Op, ro.ChangeUpdate; got != want {
+		t.Errorf("ChangeEvent.Op is %s, want %s", got, want)
+	}
+	if got, want := got.ScoreSetKeys, []string{"TaggedPost/user:2", "TaggedPost/user:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangeEvent.ScoreSetKeys is %v, want %v", got, want)
+	}
+	if want := (&TaggedPost{ID: 0, UserID: 3}); !got.HasModel || !reflect.DeepEqual(got.Model, want) {
+		t.Errorf("ChangeEvent.Model is %+v, want %+v", got.Model, want)
+	}
+}
+
+func TestRedisStore_Watch_WithMaxLen(t *testing.T) {
+	defer teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := ro.New(pool, &rotesting.Post{}, ro.WithChangeFeed(true), ro.WithChangeFeedMaxLen(1))
+
+	for i := 1; i <= 3; i++ {
+		err := store.Put(ctx, &rotesting.Post{ID: uint64(i)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	ch, err := store.Watch(ctx, "0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := receiveChange(t, ch)
+	if got.Suffix == "1" {
+		t.Errorf("Old changes should be trimmed, but got %+v", got)
+	}
+}
+
+func TestRedisStore_Watch_WithChangeFeedKeySuffix(t *testing.T) {
+	defer teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := ro.New(pool, &Tag{}, ro.WithChangeFeed(true))
+
+	ch, err := store.Watch(ctx, "0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = store.Put(ctx, []*Tag{{Name: "changes", Rank: 1}, {Name: "go", Rank: 2}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"changes", "go"} {
+		if got := receiveChange(t, ch).Suffix; got != want {
+			t.Errorf("Watch() sent a change of %s, want %s", got, want)
+		}
+	}
+
+	tag := &Tag{Name: "changes"}
+	err = store.Get(ctx, tag)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := tag.Rank, int64(1); got != want {
+		t.Errorf("Get() returned rank %d, want %d", got, want)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
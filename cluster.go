@@ -0,0 +1,95 @@
+package ro
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/ro/rq"
+)
+
+// clusterSlots is the number of hash slots of Redis Cluster.
+const clusterSlots = 16384
+
+// getKeyPrefix returns a prefix of all keys of the store.
+// It is wrapped in a hash tag when hash tags are enabled, so that all keys are assigned to the same slot.
+func (s *redisStore) getKeyPrefix() string {
+	if _, ok := hashTag(s.KeyPrefix); s.HashTagEnabled && !ok {
+		return "{" + s.KeyPrefix + "}"
+	}
+	return s.KeyPrefix
+}
+
+// checkQuerySlots returns an error when keys of the query are not in the slot of the store.
+// Multi-key commands and transactions fail with CROSSSLOT on Redis Cluster in that case.
+func (s *redisStore) checkQuerySlots(q *rq.Query) error {
+	if !s.HashTagEnabled {
+		return nil
+	}
+
+	keys := make([]string, 0, 1)
+	key, err := q.Key.Build()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	keys = append(keys, key)
+	if q.SetOp != nil {
+		for _, k := range q.SetOp.Keys {
+			key, err := k.Build()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	slot := keySlot(s.getKeyPrefix())
+	var crossed []string
+	for _, k := range keys {
+		if keySlot(k) != slot {
+			crossed = append(crossed, k)
+		}
+	}
+	if len(crossed) > 0 {
+		return &CrossSlotError{Prefix: s.getKeyPrefix(), Keys: crossed}
+	}
+	return nil
+}
+
+// hashTag returns a hash tag of the key, which is a non-empty substring between the first `{` and the next `}`.
+func hashTag(key string) (string, bool) {
+	start := strings.Index(key, "{")
+	if start < 0 {
+		return "", false
+	}
+	end := strings.Index(key[start+1:], "}")
+	if end <= 0 {
+		return "", false
+	}
+	return key[start+1 : start+1+end], true
+}
+
+// keySlot returns a hash slot of the key in the same way as Redis Cluster.
+// Only a hash tag is hashed when the key contains it.
+func keySlot(key string) uint16 {
+	if tag, ok := hashTag(key); ok {
+		key = tag
+	}
+	return crc16(key) % clusterSlots
+}
+
+// crc16 implements CRC16-CCITT (XMODEM), which is used to compute hash slots.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
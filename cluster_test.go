@@ -0,0 +1,89 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestRedisStore_WithHashTag(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &rotesting.Post{}, ro.WithHashTag(true))
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", Body: "This is a post 1.", UpdatedAt: 100},
+		{ID: 2, Title: "post 2", Body: "This is a post 2.", UpdatedAt: 200},
+	}
+
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		conn := pool.Get()
+		defer conn.Close()
+
+		got, err := redis.Strings(conn.Do("KEYS", "*"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sort.Strings(got)
+		want := []string{
+			"{Post}/id",
+			"{Post}/recent",
+			"{Post}:1",
+			"{Post}:1:scoreSetKeys",
+			"{Post}:2",
+			"{Post}:2:scoreSetKeys",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored keys are %v, want %v", got, want)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		got := []*rotesting.Post{}
+		err := store.List(context.TODO(), &got, rq.Inter(rq.SetKey{"id"}, rq.SetKey{"recent"}), rq.Weights(0, 1))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := posts; !reflect.DeepEqual(got, want) {
+			t.Errorf("List returns %v, want %v", got, want)
+		}
+	})
+
+	t.Run("cross slot query", func(t *testing.T) {
+		got := []*rotesting.Post{}
+		err := store.List(context.TODO(), &got, rq.Key("recent"), rq.KeyPrefix("Post"))
+		if !errors.Is(err, ro.ErrCrossSlot) {
+			t.Errorf("List returns %v, want ErrCrossSlot", err)
+		}
+
+		_, err = store.Count(context.TODO(), rq.Key("recent"), rq.KeyPrefix("Post"))
+		if !errors.Is(err, ro.ErrCrossSlot) {
+			t.Errorf("Count returns %v, want ErrCrossSlot", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := store.Delete(context.TODO(), posts[0])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got, want := cnt, 1; got != want {
+			t.Errorf("Count returns %d, want %d", got, want)
+		}
+	})
+}
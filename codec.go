@@ -0,0 +1,60 @@
+package ro
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Codec encodes models into strings stored in redis.
+// Stores with a codec store each model as a string instead of a hash.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is a Codec encoding models as JSON.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// encode encodes the model with the codec.
+// When the model has a version field, a copy of the model with the version is encoded so that the version is stored in the string.
+func (s *redisStore) encode(m interface{}, version int64) ([]byte, error) {
+	schema, err := getSchema(s.modelType)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid model schema")
+	}
+
+	if schema.version != nil {
+		v := reflect.New(s.modelType)
+		v.Elem().Set(reflect.Indirect(reflect.ValueOf(m)))
+		schema.version.set(v.Interface(), version)
+		m = v.Interface()
+	}
+
+	data, err := s.Codec.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode %v", m)
+	}
+	return data, nil
+}
+
+// decode decodes data into a new model.
+func (s *redisStore) decode(data []byte) (reflect.Value, error) {
+	v := reflect.New(s.modelType)
+	err := s.Codec.Unmarshal(data, v.Interface())
+	if err != nil {
+		return reflect.Value{}, errors.Wrap(err, "failed to decode")
+	}
+	return v.Elem(), nil
+}
@@ -0,0 +1,169 @@
+package ro_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+)
+
+type Article struct {
+	ID        uint64    `json:"id"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	Author    Author    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version" ro:"version"`
+}
+
+func (a *Article) GetKeySuffix() string {
+	return fmt.Sprint(a.ID)
+}
+
+func (a *Article) GetScoreMap() map[string]interface{} {
+	return map[string]interface{}{
+		"recent":                              a.CreatedAt.UnixNano(),
+		fmt.Sprintf("author:%d", a.Author.ID): a.CreatedAt.UnixNano(),
+	}
+}
+
+func TestRedisStore_WithCodec(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Article{}, ro.WithCodec(ro.JSONCodec))
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	articles := []*Article{
+		{ID: 1, Title: "article 1", Tags: []string{"go", "redis"}, Author: Author{ID: 1, Name: "alice"}, CreatedAt: now},
+		{ID: 2, Title: "article 2", Author: Author{ID: 2, Name: "bob"}, CreatedAt: now.Add(time.Hour)},
+	}
+
+	err := store.Put(context.TODO(), articles)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, a := range articles {
+		if got, want := a.Version, int64(1); got != want {
+			t.Errorf("Put() set version %d, want %d", got, want)
+		}
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	typ, err := redis.String(conn.Do("TYPE", "Article:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := typ, "string"; got != want {
+		t.Errorf("Article:1 is stored as %s, want %s", got, want)
+	}
+
+	gotArticle := &Article{ID: 1}
+	err = store.Get(context.TODO(), gotArticle)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotArticle, articles[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+
+	gotArticles := []*Article{}
+	err = store.List(context.TODO(), &gotArticles, rq.Key("recent"), rq.Reverse())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotArticles, []*Article{articles[1], articles[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	err = store.GetFields(context.TODO(), []string{"Title"}, &Article{ID: 1})
+	if err == nil {
+		t.Error("GetFields() with a codec should return an error")
+	}
+
+	err = store.Put(context.TODO(), &Article{ID: 1, Title: "stale article 1"})
+	if err == nil {
+		t.Error("Put() with a stale version should return an error")
+	}
+
+	err = store.Delete(context.TODO(), articles[1])
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	keys, err := redis.Strings(conn.Do("KEYS", "Article*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"Article/author:1", "Article/recent", "Article:1", "Article:1:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Update_WithCodec(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Article{}, ro.WithCodec(ro.JSONCodec), ro.WithTTL(time.Hour))
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	article := &Article{ID: 1, Title: "article 1", Tags: []string{"go"}, Author: Author{ID: 1, Name: "alice"}, CreatedAt: now}
+	err := store.Put(context.TODO(), article)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	update := &Article{ID: 1, Author: Author{ID: 2, Name: "bob"}, Version: 1}
+	err = store.Update(context.TODO(), update, "Author")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := update.Version, int64(2); got != want {
+		t.Errorf("Update() set version %d, want %d", got, want)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	gotArticle := &Article{ID: 1}
+	err = store.Get(context.TODO(), gotArticle)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	want := &Article{ID: 1, Title: "article 1", Tags: []string{"go"}, Author: Author{ID: 2, Name: "bob"}, CreatedAt: now, Version: 2}
+	if got := gotArticle; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored article is %v, want %v", got, want)
+	}
+
+	score, err := redis.Float64(conn.Do("ZSCORE", "Article/recent", "Article:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := score, float64(now.UnixNano()); got != want {
+		t.Errorf("Score in Article/recent is %f, want %f", got, want)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("author", 2))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+
+	ttl, err := redis.Int64(conn.Do("PTTL", "Article:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if ttl <= 0 {
+		t.Errorf("Update() removed a TTL of Article:1, got %d", ttl)
+	}
+}
@@ -2,6 +2,8 @@ package ro
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
@@ -11,20 +13,148 @@ import (
 
 // Count implements the types.Store interface.
 func (s *redisStore) Count(ctx context.Context, mods ...rq.Modifier) (int, error) {
-	conn, err := s.pool.GetContext(ctx)
+	op := &Operation{Name: "Count", Query: rq.Count(mods...)}
+	cnt, err := s.intercept(ctx, op, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return s.doCount(ctx, []rq.Modifier{queryModifier(op.Query)})
+	})
+	n, _ := cnt.(int)
+	return n, err
+}
+
+func (s *redisStore) doCount(ctx context.Context, mods []rq.Modifier) (int, error) {
+	if s.isExpirable() {
+		return s.countAlive(ctx, mods)
+	}
+
+	conn, err := s.getConn(ctx)
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to acquire a connection")
 	}
 	defer conn.Close()
 
-	cmd, err := s.injectKeyPrefix(rq.Count(mods...)).Build()
+	q, err := s.prepareQuery(rq.Count(mods...))
 	if err != nil {
 		return 0, errors.WithStack(err)
 	}
 
-	cnt, err := redis.Int(conn.Do(cmd.Name, cmd.Args...))
+	cnt, err := redis.Int(s.doQuery(conn, q))
 	if err != nil {
-		return 0, errors.Wrapf(err, "faild to execute %v", cmd)
+		return 0, errors.WithStack(err)
 	}
 	return cnt, nil
 }
+
+// countAlive counts models which are not expired yet.
+// Expired models remain in score sets until they are swept, so members expired in the expirations set are counted
+// with ZINTERSTORE in a transaction and subtracted from the count of the query.
+func (s *redisStore) countAlive(ctx context.Context, mods []rq.Modifier) (int, error) {
+	q, err := s.prepareQuery(rq.Count(mods...))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if q.IsLex() {
+		// members of lex sets are not keys, so they cannot be intersected with the expirations set
+		return s.countAliveByKeys(ctx, mods)
+	}
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	cmd, err := q.Build()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	key, err := q.Key.Build()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	expiredQuery := *q
+	expiredQuery.Key, expiredQuery.SetOp = s.newTmpKey(), nil
+	expiredKey, err := expiredQuery.Key.Build()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	expiredCmd, err := expiredQuery.Build()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	now := toMilliseconds(time.Now())
+	var cmds []*rq.Command
+	if q.SetOp != nil {
+		storeCmd, err := q.BuildSetOp()
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		cmds = append(cmds, storeCmd)
+	}
+	cntIdx := len(cmds)
+	cmds = append(cmds,
+		cmd,
+		// scores of the intersection are expiration times, and models alive at now are removed
+		&rq.Command{Name: "ZINTERSTORE", Args: []interface{}{expiredKey, 2, s.getExpirationsKey(), key, "WEIGHTS", 1, 0}},
+		&rq.Command{Name: "ZREMRANGEBYSCORE", Args: []interface{}{expiredKey, fmt.Sprintf("(%d", now), "+inf"}},
+		// scores are restored from the key, so that the range of the query is applied to expired models
+		&rq.Command{Name: "ZINTERSTORE", Args: []interface{}{expiredKey, 2, expiredKey, key, "WEIGHTS", 0, 1}},
+	)
+	expiredIdx := len(cmds)
+	cmds = append(cmds, expiredCmd, &rq.Command{Name: "DEL", Args: []interface{}{expiredKey}})
+	if q.SetOp != nil {
+		cmds = append(cmds, &rq.Command{Name: "DEL", Args: []interface{}{key}})
+	}
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		return 0, errors.Wrap(err, "faild to send MULTI command")
+	}
+	for _, c := range cmds {
+		err = conn.Send(c.Name, c.Args...)
+		if err != nil {
+			conn.Do("DISCARD")
+			return 0, errors.Wrapf(err, "faild to send %v", c)
+		}
+	}
+
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, errors.Wrap(err, "faild to EXEC commands")
+	}
+	for i, r := range replies {
+		if err, ok := r.(error); ok {
+			return 0, errors.Wrapf(err, "faild to execute %v", cmds[i])
+		}
+	}
+
+	cnt, err := redis.Int(replies[cntIdx], nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "faild to execute %v", cmd)
+	}
+	expired, err := redis.Int(replies[expiredIdx], nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "faild to execute %v", expiredCmd)
+	}
+	return cnt - expired, nil
+}
+
+// countAliveByKeys counts models which are not expired yet, by listing matched keys and filtering them.
+// It reads scores of all matched keys in the expirations set, so it costs O(N) for N matched members.
+func (s *redisStore) countAliveByKeys(ctx context.Context, mods []rq.Modifier) (int, error) {
+	listMods := make([]rq.Modifier, len(mods), len(mods)+2)
+	copy(listMods, mods)
+	listMods = append(listMods, rq.Offset(0), rq.Limit(-1))
+
+	keys, err := s.selectKeys(ctx, listMods)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to select query")
+	}
+
+	keys, err = s.rejectExpiredKeys(ctx, keys)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return len(keys), nil
+}
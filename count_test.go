@@ -135,3 +135,47 @@ func TestRedisStore_Count(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisStore_Count_WithLex(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	err := store.Put(context.TODO(), []*Author{
+		{ID: 1, Name: "bob"},
+		{ID: 2, Name: "alice"},
+		{ID: 3, Name: "bobby"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("name"), rq.LexPrefix("bob"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 2; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestRedisStore_Count_WithSetOp(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Post{})
+	err := store.Put(context.TODO(), []*Post{
+		{ID: 1, UserID: 1, CreatedAt: 3},
+		{ID: 2, UserID: 2, CreatedAt: 1},
+		{ID: 3, UserID: 3, CreatedAt: 2},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2}), rq.GtEq(2))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
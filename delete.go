@@ -3,6 +3,7 @@ package ro
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
@@ -10,34 +11,27 @@ import (
 
 // Delete implements the types.Store interface.
 func (s *redisStore) Delete(ctx context.Context, src interface{}) error {
-	keys := []string{}
-
-	rv := reflect.ValueOf(src)
-	if rv.Kind() == reflect.Slice {
-		for i := 0; i < rv.Len(); i++ {
-			m, err := s.toModel(rv.Index(i))
-			if err != nil {
-				return errors.Wrapf(err, "failed to convert to model %v", rv.Index(i).Interface())
-			}
-			key, err := s.getKey(m)
-			if err != nil {
-				return errors.Wrap(err, "failed to get key")
-			}
-			keys = append(keys, key)
-		}
-	} else {
-		m, err := s.toModel(rv)
-		if err != nil {
-			return errors.Wrapf(err, "failed to convert to model %v", rv.Interface())
-		}
-		key, err := s.getKey(m)
+	_, err := s.intercept(ctx, &Operation{Name: "Delete", Models: src}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return nil, s.doDelete(ctx, op.Models)
+	})
+	return err
+}
+
+func (s *redisStore) doDelete(ctx context.Context, src interface{}) error {
+	models, err := s.toModels(reflect.ValueOf(src))
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert to model %v", src)
+	}
+
+	keys := make([]string, len(models))
+	for i, m := range models {
+		keys[i], err = s.getKey(m)
 		if err != nil {
 			return errors.Wrap(err, "failed to get key")
 		}
-		keys = append(keys, key)
 	}
 
-	err := s.deleteByKeys(ctx, keys)
+	err = s.deleteByKeys(ctx, keys)
 	if err != nil {
 		return errors.Wrapf(err, "failed to remove by keys %v", keys)
 	}
@@ -45,44 +39,210 @@ func (s *redisStore) Delete(ctx context.Context, src interface{}) error {
 }
 
 func (s *redisStore) deleteByKeys(ctx context.Context, keys []string) error {
-	conn, err := s.pool.GetContext(ctx)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn, err := s.getConn(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to acquire a connection")
 	}
+	defer conn.Close()
 
-	keysByZsetKey := map[string][]string{}
-	for _, k := range keys {
-		zsetKeys, err := redis.Strings(conn.Do("SMEMBERS", s.getScoreSetKeysKeyByKey(k)))
+	_, err = s.deleteKeys(conn, keys, false)
+	return errors.WithStack(err)
+}
+
+// deleteKeys removes models with keys, and returns the number of removed models.
+// When expiredOnly is true, models which are not expired at the time of the transaction are kept.
+func (s *redisStore) deleteKeys(conn Conn, keys []string, expiredOnly bool) (int, error) {
+	keys = uniqueKeys(keys)
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	var (
+		n   int
+		err error
+	)
+	for i := 0; i < txRetryLimit; i++ {
+		n, err = s.delete(conn, keys, expiredOnly)
+		if errors.Cause(err) != errTxAborted {
+			break
+		}
+	}
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	s.invalidateNearCache(keys...)
+	return n, nil
+}
+
+// delete removes models with keys, their memberships, bookkeeping keys and expiration records in a transaction.
+// Models and their bookkeeping keys are watched, so that memberships added concurrently are not left.
+func (s *redisStore) delete(conn Conn, keys []string, expiredOnly bool) (int, error) {
+	err := s.watchModels(conn, keys)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if expiredOnly {
+		// models put again after they are selected have new expiration times
+		keys, err = s.selectExpiredKeys(conn, keys, time.Now())
 		if err != nil {
-			return errors.Wrapf(err, "failed to execute SMEMBERS %s", s.getScoreSetKeysKeyByKey(k))
+			conn.Do("UNWATCH")
+			return 0, errors.WithStack(err)
 		}
-		for _, zk := range zsetKeys {
-			keysByZsetKey[zk] = append(keysByZsetKey[zk], k)
+		if len(keys) == 0 {
+			conn.Do("UNWATCH")
+			return 0, nil
 		}
 	}
 
-	err = conn.Send("MULTI")
+	scoreSetKeysKeys := make([]string, len(keys))
+	lexSetMembersKeys := make([]string, 0, len(keys))
+	for i, k := range keys {
+		scoreSetKeysKeys[i] = s.getScoreSetKeysKeyByKey(k)
+		if s.isLexIndexed() {
+			lexSetMembersKeys = append(lexSetMembersKeys, s.getLexSetMembersKeyByKey(k))
+		}
+	}
+
+	zsetKeysByKey, err := s.getScoreSetKeysByKeys(conn, scoreSetKeysKeys)
 	if err != nil {
-		return errors.Wrap(err, "faild to send MULTI command")
+		conn.Do("UNWATCH")
+		return 0, errors.WithStack(err)
 	}
 
-	if len(keys) > 0 {
-		err = conn.Send("DEL", redis.Args{}.AddFlat(keys)...)
+	lexMembersByKey, err := s.getLexMembersByKeys(conn, lexSetMembersKeys)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return 0, errors.WithStack(err)
+	}
+
+	var exists []bool
+	if s.ChangeFeedEnabled {
+		exists, err = s.existModels(conn, keys)
 		if err != nil {
-			return errors.Wrapf(err, "faild to send DEL %v", keys)
+			conn.Do("UNWATCH")
+			return 0, errors.WithStack(err)
 		}
 	}
 
-	for zk, hkeys := range keysByZsetKey {
-		err = conn.Send("ZREM", redis.Args{}.Add(zk).AddFlat(hkeys)...)
+	err = conn.Send("MULTI")
+	if err != nil {
+		conn.Do("UNWATCH")
+		return 0, errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	err = s.sendDelete(conn, keys, zsetKeysByKey, lexMembersByKey, exists)
+	if err != nil {
+		conn.Do("DISCARD")
+		return 0, errors.Wrap(err, "faild to send any commands")
+	}
+
+	reply, err := conn.Do("EXEC")
+	if err != nil {
+		return 0, errors.Wrap(err, "faild to EXEC commands")
+	}
+	if reply == nil {
+		return 0, errTxAborted
+	}
+	return len(keys), nil
+}
+
+// sendDelete sends commands removing models with keys.
+// Deletions are appended to the change feed for models which exist or have bookkeeping keys.
+func (s *redisStore) sendDelete(conn Conn, keys []string, zsetKeysByKey map[string][]string, lexMembersByKey map[string]map[string]string, exists []bool) error {
+	keysByZsetKey := map[string][]string{}
+	var zsetKeys []string
+	delKeys := redis.Args{}
+	for _, k := range keys {
+		scoreSetKeysKey := s.getScoreSetKeysKeyByKey(k)
+		for _, zk := range zsetKeysByKey[scoreSetKeysKey] {
+			if _, ok := keysByZsetKey[zk]; !ok {
+				zsetKeys = append(zsetKeys, zk)
+			}
+			keysByZsetKey[zk] = append(keysByZsetKey[zk], k)
+		}
+		delKeys = delKeys.Add(k, scoreSetKeysKey)
+		if lexMembersByKey != nil {
+			lexSetMembersKey := s.getLexSetMembersKeyByKey(k)
+			for lk, member := range lexMembersByKey[lexSetMembersKey] {
+				err := conn.Send("ZREM", lk, member)
+				if err != nil {
+					return errors.Wrapf(err, "faild to send ZREM %s %s", lk, member)
+				}
+			}
+			delKeys = delKeys.Add(lexSetMembersKey)
+		}
+	}
+
+	for _, zk := range zsetKeys {
+		err := conn.Send("ZREM", redis.Args{}.Add(zk).AddFlat(keysByZsetKey[zk])...)
 		if err != nil {
-			return errors.Wrapf(err, "faild to send ZREM %s %v", zk, keys)
+			return errors.Wrapf(err, "faild to send ZREM %s %v", zk, keysByZsetKey[zk])
 		}
 	}
 
-	_, err = conn.Do("EXEC")
+	err := conn.Send("DEL", delKeys...)
+	if err != nil {
+		return errors.Wrapf(err, "faild to send DEL %v", delKeys)
+	}
+
+	expirationsKey := s.getExpirationsKey()
+	err = conn.Send("ZREM", redis.Args{}.Add(expirationsKey).AddFlat(keys)...)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute EXEC")
+		return errors.Wrapf(err, "faild to send ZREM %s %v", expirationsKey, keys)
+	}
+
+	for i, k := range keys {
+		if exists == nil || !exists[i] {
+			continue
+		}
+		err = s.sendChange(conn, ChangeDelete, k, zsetKeysByKey[s.getScoreSetKeysKeyByKey(k)], nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
 	}
 	return nil
 }
+
+// existModels reports whether each model with keys or its bookkeeping keys exist.
+func (s *redisStore) existModels(conn Conn, keys []string) ([]bool, error) {
+	for _, k := range keys {
+		err := conn.Send("EXISTS", k, s.getScoreSetKeysKeyByKey(k), s.getLexSetMembersKeyByKey(k))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to send EXISTS %s", k)
+		}
+	}
+
+	err := conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush EXISTS commands")
+	}
+
+	exists := make([]bool, len(keys))
+	for i, k := range keys {
+		n, err := redis.Int(conn.Receive())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to execute EXISTS %s", k)
+		}
+		exists[i] = n > 0
+	}
+	return exists, nil
+}
+
+// uniqueKeys returns keys without duplicates in their order.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if _, ok := seen[k]; !ok {
+			seen[k] = struct{}{}
+			unique = append(unique, k)
+		}
+	}
+	return unique
+}
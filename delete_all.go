@@ -3,6 +3,7 @@ package ro
 import (
 	"context"
 
+	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
 
 	"github.com/izumin5210/ro/rq"
@@ -10,11 +11,35 @@ import (
 
 // DeleteAll implements the types.Store interface.
 func (s *redisStore) DeleteAll(ctx context.Context, mods ...rq.Modifier) error {
-	keys, err := s.selectKeys(ctx, mods)
+	_, err := s.intercept(ctx, &Operation{Name: "DeleteAll", Query: rq.List(mods...)}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return nil, s.doDeleteAll(ctx, op.Query)
+	})
+	return err
+}
+
+func (s *redisStore) doDeleteAll(ctx context.Context, q *rq.Query) error {
+	q, err := s.prepareQuery(q)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	err = s.deleteByKeys(ctx, keys)
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	// keys are selected before the transaction, so that all keys touched by it are known to the client
+	members, err := redis.Strings(s.doQuery(conn, q))
+	if err != nil {
+		return errors.Wrap(err, "failed to select query")
+	}
+	keys := make([]string, len(members))
+	for i, m := range members {
+		keys[i] = getKeyByLexMember(m)
+	}
+
+	_, err = s.deleteKeys(conn, keys, false)
 	if err != nil {
 		return errors.Wrapf(err, "failed to remove by keys %v", keys)
 	}
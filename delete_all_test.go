@@ -2,6 +2,8 @@ package ro_test
 
 import (
 	"context"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -107,3 +109,131 @@ func TestRedisStore_DeleteAll(t *testing.T) {
 		t.Errorf("Stored keys was %d, want %d", got, want)
 	}
 }
+
+func TestRedisStore_DeleteAll_RemovesAllRelatedKeys(t *testing.T) {
+	defer teardown(t)
+	now := time.Now().UTC()
+	posts := []*rotesting.Post{
+		{
+			ID:        1,
+			Title:     "post 1",
+			Body:      "This is a post 1.",
+			UpdatedAt: now.UnixNano(),
+		},
+		{
+			ID:        2,
+			Title:     "post 2",
+			Body:      "This is a post 2.",
+			UpdatedAt: now.Add(-1 * 60 * time.Second).UnixNano(),
+		},
+		{
+			ID:        3,
+			Title:     "post 3",
+			Body:      "This is a post 3.",
+			UpdatedAt: now.Add(1 * 60 * time.Second).UnixNano(),
+		},
+	}
+
+	store := ro.New(pool, &rotesting.Post{})
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = store.DeleteAll(context.TODO(), rq.Key("recent"), rq.LtEq(now.UnixNano()))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"Post/id", "Post/recent", "Post:3", "Post:3:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+
+	for _, k := range []string{"Post/id", "Post/recent"} {
+		members, err := redis.Strings(conn.Do("ZRANGE", k, 0, -1))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got, want := members, []string{"Post:3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s contains %v, want %v", k, got, want)
+		}
+	}
+}
+
+func TestRedisStore_DeleteAll_WithLex(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	err := store.Put(context.TODO(), []*Author{
+		{ID: 1, Name: "bob"},
+		{ID: 2, Name: "alice"},
+		{ID: 3, Name: "bobby"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = store.DeleteAll(context.TODO(), rq.Key("name"), rq.LexPrefix("bob"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"Author/id", "Author/name", "Author:2", "Author:2:lexSetMembers", "Author:2:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+
+	members, err := redis.Strings(conn.Do("ZRANGE", "Author/name", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := members, []string{"alice\x00Author:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Author/name contains %q, want %q", got, want)
+	}
+}
+
+func TestRedisStore_DeleteAll_WithSetOp(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Post{})
+	err := store.Put(context.TODO(), []*Post{
+		{ID: 1, UserID: 1, CreatedAt: 3},
+		{ID: 2, UserID: 2, CreatedAt: 1},
+		{ID: 3, UserID: 3, CreatedAt: 2},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = store.DeleteAll(context.TODO(), rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2}))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"Post/recent", "Post/user:3", "Post:3", "Post:3:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+}
@@ -2,6 +2,8 @@ package ro_test
 
 import (
 	"context"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -199,3 +201,55 @@ func TestRedisStore_Remove_WhenDisableToStoreToHash(t *testing.T) {
 		t.Errorf("Stored keys was %d, want %d", got, want)
 	}
 }
+
+func TestRedisStore_Delete_RemovesAllRelatedKeys(t *testing.T) {
+	defer teardown(t)
+	now := time.Now().UTC()
+	posts := []*rotesting.Post{
+		{
+			ID:        1,
+			Title:     "post 1",
+			Body:      "This is a post 1.",
+			UpdatedAt: now.UnixNano(),
+		},
+		{
+			ID:        2,
+			Title:     "post 2",
+			Body:      "This is a post 2.",
+			UpdatedAt: now.Add(-60 * 60 * 24 * time.Second).UnixNano(),
+		},
+	}
+
+	store := ro.New(pool, &rotesting.Post{})
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = store.Delete(context.TODO(), posts[0])
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"Post/id", "Post/recent", "Post:2", "Post:2:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+
+	for _, k := range []string{"Post/id", "Post/recent"} {
+		members, err := redis.Strings(conn.Do("ZRANGE", k, 0, -1))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got, want := members, []string{"Post:2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s contains %v, want %v", k, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrConflict is a sentinel of errors returned when a stored version of a model differs from a version of the model being put.
+// Use errors.Is to check whether an error is caused by a conflict.
+var ErrConflict = errors.New("version conflict")
+
+// ConflictError represents a version conflict detected by Put.
+type ConflictError struct {
+	Key           string
+	Version       int64
+	StoredVersion int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s is stored with version %d, but got version %d", ErrConflict, e.Key, e.StoredVersion, e.Version)
+}
+
+// Is reports whether the target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ErrCrossSlot is a sentinel of errors returned when a query refers to keys in other slots than keys of a store on Redis Cluster.
+// Use errors.Is to check whether an error is caused by cross slot keys.
+var ErrCrossSlot = errors.New("cross slot keys")
+
+// CrossSlotError represents keys of a query which are not in the same slot as keys of a store.
+type CrossSlotError struct {
+	Prefix string
+	Keys   []string
+}
+
+func (e *CrossSlotError) Error() string {
+	return fmt.Sprintf("%s: %s are not in the slot of %s", ErrCrossSlot, strings.Join(e.Keys, ", "), e.Prefix)
+}
+
+// Is reports whether the target is ErrCrossSlot.
+func (e *CrossSlotError) Is(target error) bool {
+	return target == ErrCrossSlot
+}
+
+// ErrNotFound is a sentinel of errors returned when models are not stored.
+// Use errors.Is to check whether an error is caused by missing models.
+var ErrNotFound = errors.New("not found")
+
+// NotFoundError represents models missing in Get or Update.
+// Suffixes are key suffixes of the missing models.
+type NotFoundError struct {
+	Suffixes []string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrNotFound, strings.Join(e.Suffixes, ", "))
+}
+
+// Is reports whether the target is ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ErrLoadedTTLUnsupported is returned by loads of read-through stores created with WithLoadedTTL,
+// when the underlying store cannot record expirations of models.
+var ErrLoadedTTLUnsupported = errors.New("WithLoadedTTL requires a store created by NewTyped with WithTTL or Expirer")
+
+// ErrLexPage is returned by ListPage for queries with lexicographical ranges, which cannot be paged by score cursors.
+// Use List with Offset and Limit to page them.
+var ErrLexPage = errors.New("ListPage does not support lexicographical ranges")
@@ -82,7 +82,7 @@ func cleanup() {
 // Examples
 // ----------------------------------------------------------------
 
-func Example_Store_Set() {
+func ExampleStore_Put() {
 	defer cleanup()
 	postStore = ro.New(pool, &Post{})
 
@@ -121,7 +121,7 @@ func Example_Store_Set() {
 	// This is a post 1
 }
 
-func Example_Store_Get() {
+func ExampleStore_Get() {
 	setup()
 	defer cleanup()
 
@@ -132,7 +132,7 @@ func Example_Store_Get() {
 	// This is a post 1
 }
 
-func Example_Store_List() {
+func ExampleStore_List() {
 	setup()
 	defer cleanup()
 
@@ -145,7 +145,7 @@ func Example_Store_List() {
 	// This is a post 1
 }
 
-func Example_Store_Count() {
+func ExampleStore_Count() {
 	setup()
 	defer cleanup()
 
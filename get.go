@@ -7,42 +7,240 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (s *redisStore) Get(ctx context.Context, dests ...Model) error {
-	conn, err := s.pool.GetContext(ctx)
+// Get implements the types.Store interface.
+func (s *redisStore) Get(ctx context.Context, dests ...interface{}) error {
+	return s.GetFields(ctx, nil, dests...)
+}
+
+// GetFields implements the types.Store interface.
+func (s *redisStore) GetFields(ctx context.Context, fields []string, dests ...interface{}) error {
+	suffixes := make([]string, len(dests), len(dests))
+	for i, m := range dests {
+		var err error
+		suffixes[i], err = getKeySuffix(m)
+		if err != nil {
+			return errors.Wrap(err, "failed to get key")
+		}
+	}
+
+	_, err := s.getFields(ctx, suffixes, fields, dests)
+	return err
+}
+
+// getFields reads models with key suffixes into dests through interceptors, and reports whether each of models exists.
+func (s *redisStore) getFields(ctx context.Context, suffixes, fields []string, dests []interface{}) ([]bool, error) {
+	var found []bool
+	op := &Operation{Name: "Get", Models: dests, Suffixes: suffixes, Fields: fields}
+	_, err := s.intercept(ctx, op, func(ctx context.Context, op *Operation) (interface{}, error) {
+		hashFields, err := lookupHashFields(s.modelType, op.Fields)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		found, err = s.getBySuffixes(ctx, op.Suffixes, hashFields, dests)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, newNotFoundError(op.Suffixes, found)
+	})
+	return found, err
+}
+
+// getBySuffixes scans hashes into dests, and reports whether each of hashes exists.
+// Dests of missing hashes are left untouched.
+func (s *redisStore) getBySuffixes(ctx context.Context, suffixes []string, hashFields []string, dests []interface{}) ([]bool, error) {
+	keys := make([]string, len(suffixes))
+	for i, suffix := range suffixes {
+		key, err := s.getKeyBySuffix(suffix)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get key")
+		}
+		keys[i] = key
+	}
+
+	conn, err := s.getConn(ctx)
 	if err != nil {
-		return errors.Wrap(err, "failed to acquire a connection")
+		return nil, errors.Wrap(err, "failed to acquire a connection")
 	}
 	defer conn.Close()
 
-	keys := make([]string, len(dests), len(dests))
+	return s.readModels(conn, keys, hashFields, dests)
+}
 
-	for i, m := range dests {
-		key, err := s.getKey(m)
+// readModels reads models stored with keys into dests, and reports whether each of models exists.
+// Dests of missing models are left untouched.
+func (s *redisStore) readModels(conn Conn, keys []string, hashFields []string, dests []interface{}) ([]bool, error) {
+	if s.Codec != nil && len(hashFields) > 0 {
+		return nil, errors.New("field projection is not supported with codecs")
+	}
+
+	values, found, err := s.readValues(conn, keys, hashFields)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for i, d := range dests {
+		if !found[i] {
+			continue
+		}
+		err = s.scanValue(values[i], d)
 		if err != nil {
-			return errors.Wrap(err, "failed to get key")
+			return nil, errors.Wrapf(err, "faild to scan struct %s %x", keys[i], values[i])
 		}
-		keys[i] = key
-		err = conn.Send("HGETALL", key)
+	}
+
+	return found, nil
+}
+
+// readValues reads values stored with keys through the near cache, and reports whether each of them exists.
+// Values are field-value pairs of hashes, or strings encoded with the codec.
+func (s *redisStore) readValues(conn Conn, keys []string, hashFields []string) ([]interface{}, []bool, error) {
+	if s.NearCache != nil && len(hashFields) == 0 {
+		return s.NearCache.read(s, conn, keys)
+	}
+	return s.fetchValues(conn, keys, hashFields)
+}
+
+// fetchValues reads values stored with keys from redis, and reports whether each of them exists.
+func (s *redisStore) fetchValues(conn Conn, keys []string, hashFields []string) ([]interface{}, []bool, error) {
+	if s.Codec != nil {
+		return fetchStrings(conn, keys)
+	}
+
+	pairs, found, err := readHashes(conn, keys, hashFields)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	values := make([]interface{}, len(pairs))
+	for i, p := range pairs {
+		values[i] = p
+	}
+	return values, found, nil
+}
+
+// scanValue scans a value read by fetchValues into the dest.
+func (s *redisStore) scanValue(value interface{}, dest interface{}) error {
+	if s.Codec != nil {
+		return errors.Wrap(s.Codec.Unmarshal(value.([]byte), dest), "failed to decode")
+	}
+	return errors.WithStack(scanHash(value.([]interface{}), dest))
+}
+
+// fetchStrings reads strings stored with keys, and reports whether each of them exists.
+func fetchStrings(conn Conn, keys []string) ([]interface{}, []bool, error) {
+	values := make([]interface{}, len(keys))
+	found := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return values, found, nil
+	}
+
+	data, err := redis.ByteSlices(conn.Do("MGET", redis.Args{}.AddFlat(keys)...))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "faild to execute MGET %v", keys)
+	}
+
+	for i, d := range data {
+		if d != nil {
+			values[i], found[i] = d, true
+		}
+	}
+	return values, found, nil
+}
+
+// newNotFoundError returns a NotFoundError containing suffixes which are not found, or nil when all of them are found.
+func newNotFoundError(suffixes []string, found []bool) error {
+	var missing []string
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, suffixes[i])
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &NotFoundError{Suffixes: missing}
+}
+
+// readHashes reads hashes with HGETALL, or HMGET when hash fields are specified.
+// It returns field-value pairs which can be scanned with scanHash, and whether each of hashes exists.
+func readHashes(conn Conn, keys []string, hashFields []string) ([][]interface{}, []bool, error) {
+	for _, key := range keys {
+		err := sendReadHash(conn, key, hashFields)
 		if err != nil {
-			return errors.Wrapf(err, "faild to send HGETALL %s", key)
+			return nil, nil, errors.WithStack(err)
 		}
 	}
 
-	err = conn.Flush()
+	err := conn.Flush()
 	if err != nil {
-		return errors.Wrap(err, "faild to flush HGETALL commands")
+		return nil, nil, errors.Wrap(err, "faild to flush commands reading hashes")
 	}
 
-	for i, d := range dests {
-		v, err := redis.Values(conn.Receive())
+	values := make([][]interface{}, len(keys))
+	found := make([]bool, len(keys))
+	var uncertain []int
+	for i := range keys {
+		values[i], err = receiveHash(conn, hashFields)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "faild to receive or cast redis command result")
+		}
+		found[i] = len(values[i]) > 0
+		if !found[i] && len(hashFields) > 0 {
+			uncertain = append(uncertain, i)
+		}
+	}
+
+	// HMGET cannot tell a missing hash from a hash without the fields
+	for _, i := range uncertain {
+		err = conn.Send("EXISTS", keys[i])
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "faild to send EXISTS %s", keys[i])
+		}
+	}
+	if len(uncertain) > 0 {
+		err = conn.Flush()
 		if err != nil {
-			return errors.Wrap(err, "faild to receive or cast redis command result")
+			return nil, nil, errors.Wrap(err, "faild to flush EXISTS commands")
 		}
-		err = redis.ScanStruct(v, d)
+	}
+	for _, i := range uncertain {
+		found[i], err = redis.Bool(conn.Receive())
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "faild to execute EXISTS %s", keys[i])
+		}
+	}
+
+	return values, found, nil
+}
+
+// sendReadHash sends HGETALL, or HMGET when hash fields are specified.
+func sendReadHash(conn Conn, key string, hashFields []string) error {
+	if len(hashFields) == 0 {
+		err := conn.Send("HGETALL", key)
 		if err != nil {
-			return errors.Wrapf(err, "faild to scan struct %s %x", keys[i], v)
+			return errors.Wrapf(err, "faild to send HGETALL %s", key)
 		}
+		return nil
 	}
 
+	err := conn.Send("HMGET", redis.Args{}.Add(key).AddFlat(hashFields)...)
+	if err != nil {
+		return errors.Wrapf(err, "faild to send HMGET %s %v", key, hashFields)
+	}
 	return nil
 }
+
+// receiveHash receives a reply of a command sent by sendReadHash as field-value pairs, which can be scanned with scanHash.
+func receiveHash(conn Conn, hashFields []string) ([]interface{}, error) {
+	v, err := redis.Values(conn.Receive())
+	if err != nil || len(hashFields) == 0 {
+		return v, err
+	}
+
+	pairs := make([]interface{}, 0, 2*len(v))
+	for i, fv := range v {
+		if fv != nil {
+			pairs = append(pairs, []byte(hashFields[i]), fv)
+		}
+	}
+	return pairs, nil
+}
@@ -2,6 +2,7 @@ package ro_test
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -59,3 +60,88 @@ func TestRedisStore_Get(t *testing.T) {
 		}
 	})
 }
+
+func TestRedisStore_GetFields(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &rotesting.Post{})
+	post := &rotesting.Post{ID: 1, Title: "post 1", Body: "This is a post 1.", UpdatedAt: 100}
+
+	conn := pool.Get()
+	defer conn.Close()
+	conn.Do("HMSET", redis.Args{}.Add("Post:1").AddFlat(post)...)
+
+	t.Run("with hash field names", func(t *testing.T) {
+		gotPost := &rotesting.Post{ID: 1}
+		err := store.GetFields(context.TODO(), []string{"id", "title"}, gotPost)
+
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got, want := gotPost, (&rotesting.Post{ID: 1, Title: "post 1"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored post is %v, want %v", got, want)
+		}
+	})
+
+	t.Run("with struct field names", func(t *testing.T) {
+		gotPost := &rotesting.Post{ID: 1}
+		err := store.GetFields(context.TODO(), []string{"UpdatedAt"}, gotPost)
+
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got, want := gotPost, (&rotesting.Post{ID: 1, UpdatedAt: 100}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored post is %v, want %v", got, want)
+		}
+	})
+
+	t.Run("with unknown fields", func(t *testing.T) {
+		err := store.GetFields(context.TODO(), []string{"unknown"}, &rotesting.Post{ID: 1})
+
+		if err == nil {
+			t.Error("GetFields() with unknown fields should return an error")
+		}
+	})
+}
+
+func TestRedisStore_Get_WhenNotFound(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &rotesting.Post{})
+	post := &rotesting.Post{ID: 1, Title: "post 1"}
+
+	conn := pool.Get()
+	defer conn.Close()
+	conn.Do("HMSET", redis.Args{}.Add("Post:1").AddFlat(post)...)
+
+	gotPosts := []*rotesting.Post{{ID: 1}, {ID: 2}, {ID: 3}}
+	err := store.Get(context.TODO(), gotPosts[0], gotPosts[1], gotPosts[2])
+
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Get() returned %v, want ErrNotFound", err)
+	}
+	var notFoundErr *ro.NotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Errorf("Get() returned %v, want NotFoundError", err)
+	} else if got, want := notFoundErr.Suffixes, []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NotFoundError.Suffixes is %v, want %v", got, want)
+	}
+	if got, want := gotPosts[0], post; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored post is %v, want %v", got, want)
+	}
+
+	t.Run("with fields which are not stored", func(t *testing.T) {
+		gotPost := &rotesting.Post{ID: 1}
+		err := store.GetFields(context.TODO(), []string{"body"}, gotPost)
+
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("with fields of missing models", func(t *testing.T) {
+		err := store.GetFields(context.TODO(), []string{"title"}, &rotesting.Post{ID: 2})
+
+		if !errors.Is(err, ro.ErrNotFound) {
+			t.Errorf("GetFields() returned %v, want ErrNotFound", err)
+		}
+	})
+}
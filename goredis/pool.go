@@ -0,0 +1,172 @@
+// Package goredis provides ro.Pool backed by go-redis.
+package goredis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/izumin5210/ro"
+)
+
+// Client is a go-redis client which provides dedicated connections, which is implemented by *redis.Client.
+// Clients should use RESP2 (redis.Options.Protocol = 2), since replies of some commands have different shapes in RESP3
+// (e.g. HGETALL returns a map and ZRANGE WITHSCORES returns pairs).
+type Client interface {
+	Conn() *redis.Conn
+	Options() *redis.Options
+}
+
+var (
+	// ErrProtocol is returned by New when the client does not use RESP2.
+	ErrProtocol = errors.New("goredis: client should use RESP2 (redis.Options.Protocol = 2)")
+
+	errClosed = errors.New("goredis: connection closed")
+)
+
+type pool struct {
+	client Client
+}
+
+// New returns ro.Pool which gets connections from the go-redis client.
+// Each connection is dedicated to a store operation, so transactions with WATCH and MULTI work as with redigo.
+// It returns ErrProtocol when the client uses RESP3, which is the default of go-redis.
+func New(client Client) (ro.Pool, error) {
+	if client.Options().Protocol != 2 {
+		return nil, ErrProtocol
+	}
+	return &pool{client: client}, nil
+}
+
+// GetContext implements the ro.Pool interface.
+func (p *pool) GetContext(ctx context.Context) (ro.Conn, error) {
+	return &conn{ctx: ctx, conn: p.client.Conn()}, nil
+}
+
+// conn emulates pipelining of redigo.
+// Commands passed to Send are queued, written by Flush in a pipeline, and their replies are read by Receive.
+type conn struct {
+	ctx     context.Context
+	conn    *redis.Conn
+	pending []*redis.Cmd
+	flushed []*redis.Cmd
+	err     error
+}
+
+// Do implements the ro.Conn interface.
+func (c *conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName != "" {
+		err := c.Send(commandName, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := c.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reply    interface{}
+		replyErr error
+	)
+	for len(c.flushed) > 0 {
+		r, err := c.Receive()
+		var rerr redis.Error
+		if err != nil && !errors.As(err, &rerr) {
+			return nil, err
+		}
+		if err != nil && replyErr == nil {
+			replyErr = err
+		}
+		reply = r
+	}
+	return reply, replyErr
+}
+
+// Send implements the ro.Conn interface.
+func (c *conn) Send(commandName string, args ...interface{}) error {
+	c.pending = append(c.pending, redis.NewCmd(c.ctx, append([]interface{}{commandName}, args...)...))
+	return nil
+}
+
+// Flush implements the ro.Conn interface.
+func (c *conn) Flush() error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	cmds := c.pending
+	c.pending = nil
+
+	pipe := c.conn.Pipeline()
+	for _, cmd := range cmds {
+		err := pipe.Process(c.ctx, cmd)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := pipe.Exec(c.ctx)
+	var rerr redis.Error
+	if err != nil && !errors.As(err, &rerr) {
+		c.err = err
+		return err
+	}
+	c.flushed = append(c.flushed, cmds...)
+	return nil
+}
+
+// Receive implements the ro.Conn interface.
+func (c *conn) Receive() (interface{}, error) {
+	if len(c.flushed) == 0 {
+		err := c.Flush()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(c.flushed) == 0 {
+		return nil, errors.New("goredis: no pending replies")
+	}
+	cmd := c.flushed[0]
+	c.flushed = c.flushed[1:]
+
+	v, err := cmd.Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return convertReply(v), nil
+}
+
+// Close implements the ro.Conn interface.
+func (c *conn) Close() error {
+	c.pending, c.flushed = nil, nil
+	if c.err == nil {
+		c.err = errClosed
+	}
+	return c.conn.Close()
+}
+
+// Err implements the ro.Conn interface.
+// It returns an error which made the connection unusable, such as a network error.
+func (c *conn) Err() error {
+	return c.err
+}
+
+// convertReply converts a reply into types of redigo replies, since go-redis reads bulk strings as strings.
+func convertReply(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = convertReply(e)
+		}
+		return values
+	default:
+		return v
+	}
+}
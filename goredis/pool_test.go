@@ -0,0 +1,162 @@
+package goredis_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/goredis"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+var pool *rotesting.Pool
+
+func TestMain(m *testing.M) {
+	pool = rotesting.MustCreateLocal()
+
+	code := m.Run()
+
+	pool.MustClose()
+
+	os.Exit(code)
+}
+
+func TestPool(t *testing.T) {
+	defer pool.Cleanup()
+
+	opts, err := redis.ParseURL(pool.URL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opts.Protocol = 2
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	p, err := goredis.New(client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	store := ro.New(p, &rotesting.Post{})
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", Body: "This is a post 1.", UpdatedAt: 100},
+		{ID: 2, Title: "post 2", Body: "This is a post 2.", UpdatedAt: 200},
+		{ID: 3, Title: "post 3", Body: "This is a post 3.", UpdatedAt: 300},
+	}
+
+	err = store.Put(ctx, posts)
+	if err != nil {
+		t.Fatalf("Put returns an error: %v", err)
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		got := []*rotesting.Post{{ID: 1}, {ID: 2}}
+		err := store.Get(ctx, got[0], got[1])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := posts[:2]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Get returns %v, want %v", got, want)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		got := []*rotesting.Post{}
+		err := store.List(ctx, &got, rq.Key("recent"), rq.Reverse(), rq.Limit(2))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := []*rotesting.Post{posts[2], posts[1]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("List returns %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		err := store.Update(ctx, &rotesting.Post{ID: 1, Title: "updated"}, "Title")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := &rotesting.Post{ID: 1}
+		err = store.Get(ctx, got)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got, want := got.Title, "updated"; got != want {
+			t.Errorf("Title is %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Delete and DeleteAll", func(t *testing.T) {
+		err := store.Delete(ctx, posts[0])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		err = store.DeleteAll(ctx, rq.Inter(rq.SetKey{"id"}, rq.SetKey{"recent"}), rq.Gt(250))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cnt, err := store.Count(ctx, rq.Key("id"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got, want := cnt, 1; got != want {
+			t.Errorf("Count returns %d, want %d", got, want)
+		}
+	})
+
+	t.Run("Get missing", func(t *testing.T) {
+		err := store.Get(ctx, &rotesting.Post{ID: 1})
+		if !errors.Is(err, ro.ErrNotFound) {
+			t.Errorf("Get returns %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestPool_Err(t *testing.T) {
+	opts, err := redis.ParseURL(pool.URL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opts.Protocol = 2
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	p, err := goredis.New(client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err returns %v, want nil", err)
+	}
+
+	conn.Close()
+	if err := conn.Err(); err == nil {
+		t.Error("Err should return an error after Close")
+	}
+}
+
+func TestNew_WithRESP3(t *testing.T) {
+	opts, err := redis.ParseURL(pool.URL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	_, err = goredis.New(client)
+	if !errors.Is(err, goredis.ErrProtocol) {
+		t.Errorf("New returns %v, want ErrProtocol", err)
+	}
+}
@@ -0,0 +1,54 @@
+package ro
+
+import (
+	"context"
+
+	"github.com/izumin5210/ro/rq"
+)
+
+// Operation describes a store operation passed to interceptors.
+type Operation struct {
+	// Name is a name of the Store method, e.g. "Put" or "List". GetFields is named "Get".
+	Name string
+	// Models are models passed to Put, Update and Delete, dests of Get, or a pointer to a dest slice of List and ListPage.
+	Models interface{}
+	// Suffixes are key suffixes of models read by Get.
+	Suffixes []string
+	// Fields are fields read by Get or written by Update.
+	Fields []string
+	// Query is a query of List, ListPage, DeleteAll and Count.
+	// Interceptors can modify it before calling next.
+	Query *rq.Query
+}
+
+// Invoker executes an operation.
+// It returns the number of models for Count, Sweep and Reindex, a cursor of the next page for ListPage, a *VerifyReport for Verify, and nil for the others.
+type Invoker func(ctx context.Context, op *Operation) (interface{}, error)
+
+// Interceptor intercepts store operations like unary interceptors of gRPC.
+// It should call next to execute the operation, and return its result.
+type Interceptor func(ctx context.Context, op *Operation, next Invoker) (interface{}, error)
+
+// intercept executes the operation with invoke through interceptors of the store.
+// Interceptors are called in order, so the first one is the outermost.
+// A name of the operation is set to the context, and can be read with OperationName.
+func (s *redisStore) intercept(ctx context.Context, op *Operation, invoke Invoker) (interface{}, error) {
+	ctx = withOperationName(ctx, op.Name)
+	for i := len(s.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := s.Interceptors[i], invoke
+		invoke = func(ctx context.Context, op *Operation) (interface{}, error) {
+			return interceptor(ctx, op, next)
+		}
+	}
+	return invoke(ctx, op)
+}
+
+// queryModifier returns a modifier replacing parameters of a query with the query of an operation.
+// A command type of the query is kept, since Count selects keys with list queries when models can expire.
+func queryModifier(q *rq.Query) rq.Modifier {
+	return func(dest *rq.Query) {
+		typ := dest.Type
+		*dest = *q
+		dest.Type = typ
+	}
+}
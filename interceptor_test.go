@@ -0,0 +1,111 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestRedisStore_WithInterceptors(t *testing.T) {
+	defer teardown(t)
+
+	var calls []string
+	recorder := func(name string) ro.Interceptor {
+		return func(ctx context.Context, op *ro.Operation, next ro.Invoker) (interface{}, error) {
+			calls = append(calls, name+":"+op.Name)
+			return next(ctx, op)
+		}
+	}
+	errForbidden := errors.New("forbidden")
+	authorizer := func(ctx context.Context, op *ro.Operation, next ro.Invoker) (interface{}, error) {
+		if op.Name == "Delete" {
+			return nil, errForbidden
+		}
+		if op.Query != nil {
+			rq.Limit(1)(op.Query)
+		}
+		return next(ctx, op)
+	}
+
+	store := ro.New(pool, &rotesting.Post{}, ro.WithInterceptors(recorder("outer"), recorder("inner"), authorizer))
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: 100},
+		{ID: 2, Title: "post 2", UpdatedAt: 200},
+	}
+
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts := []*rotesting.Post{}
+	err = store.List(context.TODO(), &gotPosts, rq.Key("recent"), rq.Reverse())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, posts[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("List returns %v, want %v", got, want)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 2; got != want {
+		t.Errorf("Count returns %d, want %d", got, want)
+	}
+
+	err = store.Delete(context.TODO(), posts[0])
+	if got, want := err, errForbidden; got != want {
+		t.Errorf("Delete returns %v, want %v", got, want)
+	}
+
+	want := []string{
+		"outer:Put", "inner:Put",
+		"outer:List", "inner:List",
+		"outer:Count", "inner:Count",
+		"outer:Delete", "inner:Delete",
+	}
+	if got := calls; !reflect.DeepEqual(got, want) {
+		t.Errorf("Interceptors are called with %v, want %v", got, want)
+	}
+}
+
+func TestTypedStore_WithInterceptors(t *testing.T) {
+	defer teardown(t)
+
+	var ops []*ro.Operation
+	interceptor := func(ctx context.Context, op *ro.Operation, next ro.Invoker) (interface{}, error) {
+		ops = append(ops, op)
+		return next(ctx, op)
+	}
+
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithInterceptors(interceptor))
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = store.GetFields(context.TODO(), []string{"title"}, "1", "2")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("GetFields returns %v, want ErrNotFound", err)
+	}
+
+	if got, want := len(ops), 2; got != want {
+		t.Fatalf("Interceptor is called %d times, want %d", got, want)
+	}
+	if got, want := ops[1].Name, "Get"; got != want {
+		t.Errorf("Operation name is %q, want %q", got, want)
+	}
+	if got, want := ops[1].Suffixes, []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Operation suffixes are %v, want %v", got, want)
+	}
+	if got, want := ops[1].Fields, []string{"title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Operation fields are %v, want %v", got, want)
+	}
+}
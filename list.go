@@ -4,7 +4,6 @@ import (
 	"context"
 	"reflect"
 
-	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
 
 	"github.com/izumin5210/ro/rq"
@@ -12,46 +11,81 @@ import (
 
 // List implements the types.Store interface.
 func (s *redisStore) List(ctx context.Context, dest interface{}, mods ...rq.Modifier) error {
+	_, err := s.intercept(ctx, &Operation{Name: "List", Models: dest, Query: rq.List(mods...)}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return nil, s.doList(ctx, op.Models, []rq.Modifier{queryModifier(op.Query)})
+	})
+	return err
+}
+
+func (s *redisStore) doList(ctx context.Context, dest interface{}, mods []rq.Modifier) error {
+	dt, err := getSliceValue(dest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	hashFields, err := lookupHashFields(s.modelType, rq.List(mods...).Fields)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	keys, err := s.selectKeys(ctx, mods)
+	if err != nil {
+		return errors.Wrap(err, "failed to select query")
+	}
+
+	return s.appendByKeys(ctx, dt, keys, hashFields)
+}
+
+func getSliceValue(dest interface{}) (reflect.Value, error) {
 	dt := reflect.ValueOf(dest)
 	if dt.Kind() != reflect.Ptr || dt.IsNil() {
-		return errors.New("must pass a slice ptr")
+		return reflect.Value{}, errors.New("must pass a slice ptr")
 	}
 	dt = dt.Elem()
 	if dt.Kind() != reflect.Slice {
-		return errors.New("must pass a slice ptr")
+		return reflect.Value{}, errors.New("must pass a slice ptr")
 	}
+	return dt, nil
+}
 
-	keys, err := s.selectKeys(ctx, mods)
-	if err != nil {
-		return errors.Wrap(err, "failed to select query")
+// appendByKeys appends models stored with keys to the slice.
+// Only the hash fields are read when they are specified.
+// Keys whose hashes no longer exist are skipped.
+func (s *redisStore) appendByKeys(ctx context.Context, dt reflect.Value, keys []string, hashFields []string) error {
+	if s.isExpirable() {
+		var err error
+		keys, err = s.rejectExpiredKeys(ctx, keys)
+		if err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
-	conn, err := s.pool.GetContext(ctx)
+	conn, err := s.getConn(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to acquire a connection")
 	}
 	defer conn.Close()
 
-	for _, key := range keys {
-		err := conn.Send("HGETALL", key)
-		if err != nil {
-			return errors.Wrapf(err, "failed to send HGETALL %s", key)
-		}
-	}
+	et := dt.Type().Elem()
+	vt := indirectType(et)
 
-	conn.Flush()
+	dests := make([]interface{}, len(keys))
+	for i := range keys {
+		dests[i] = reflect.New(vt).Interface()
+	}
 
-	vt := dt.Type().Elem().Elem()
+	found, err := s.readModels(conn, keys, hashFields, dests)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
-	for _, key := range keys {
-		v, err := redis.Values(conn.Receive())
-		if err != nil {
-			return errors.Wrap(err, "faild to receive or cast redis command result")
+	for i := range keys {
+		if !found[i] {
+			continue
 		}
-		vv := reflect.New(vt)
-		err = redis.ScanStruct(v, vv.Interface())
-		if err != nil {
-			return errors.Wrapf(err, "faild to scan struct %s %x", key, v)
+		vv := reflect.ValueOf(dests[i])
+		if et.Kind() != reflect.Ptr {
+			vv = vv.Elem()
 		}
 		dt.Set(reflect.Append(dt, vv))
 	}
@@ -0,0 +1,325 @@
+package ro
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/ro/rq"
+)
+
+// ListPage implements the types.Store interface.
+func (s *redisStore) ListPage(ctx context.Context, dest interface{}, mods ...rq.Modifier) (string, error) {
+	op := &Operation{Name: "ListPage", Models: dest, Query: rq.List(mods...)}
+	next, err := s.intercept(ctx, op, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return s.doListPage(ctx, op.Models, []rq.Modifier{queryModifier(op.Query)})
+	})
+	cursor, _ := next.(string)
+	return cursor, err
+}
+
+func (s *redisStore) doListPage(ctx context.Context, dest interface{}, mods []rq.Modifier) (string, error) {
+	dt, err := getSliceValue(dest)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	hashFields, err := lookupHashFields(s.modelType, rq.List(mods...).Fields)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	keys, next, err := s.selectPage(ctx, mods)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to select query")
+	}
+
+	err = s.appendByKeys(ctx, dt, keys, hashFields)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return next, nil
+}
+
+type pageEntry struct {
+	member string
+	score  string
+}
+
+// pageSetOpTTL bounds a lifetime of a combined set stored while a page is selected, in case it is not removed.
+const pageSetOpTTL = time.Minute
+
+// selectPage selects keys after (or before) a cursor of the query, and returns them with a cursor to the next page.
+func (s *redisStore) selectPage(ctx context.Context, mods []rq.Modifier) ([]string, string, error) {
+	q, err := s.prepareQuery(rq.List(mods...))
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	if q.IsLex() {
+		return nil, "", errors.WithStack(ErrLexPage)
+	}
+
+	cursor, err := q.GetCursor()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	// commands are built in the order of the page, and the cursor is handled here
+	backward := q.Backward
+	q.Reverse = q.IsReversed()
+	q.Cursor, q.Backward, q.WithScores = "", false, true
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	limit := q.Limit
+	if limit >= 0 {
+		// fetch an extra entry to know whether the next page exists
+		q.Limit = limit + 1
+	}
+
+	var entries []*pageEntry
+	if cursor == nil {
+		values, err := redis.Strings(s.doQuery(conn, q))
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		entries = toPageEntries(values)
+	} else {
+		entries, err = s.selectAfterCursor(conn, q, cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+	}
+
+	var next string
+	if limit >= 0 && len(entries) > limit {
+		entries = entries[:limit]
+		if limit > 0 {
+			last := entries[limit-1]
+			next = (&rq.Cursor{Score: last.score, Member: last.member}).String()
+		}
+	}
+
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		if backward {
+			keys[len(entries)-1-i] = getKeyByLexMember(e.member)
+		} else {
+			keys[i] = getKeyByLexMember(e.member)
+		}
+	}
+
+	return keys, next, nil
+}
+
+// selectAfterCursor selects entries following the cursor in the order of the query.
+// Members which have the same score as the cursor are selected by selectTies, and the others are selected with an exclusive bound of the cursor score.
+func (s *redisStore) selectAfterCursor(conn Conn, q *rq.Query, cursor *rq.Cursor) ([]*pageEntry, error) {
+	if q.SetOp != nil {
+		// the combined set is ranged by several commands, so it is stored until the page is selected
+		storeCmd, err := q.BuildSetOp()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		dest := storeCmd.Args[0]
+		err = storeSetOp(conn, storeCmd)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer conn.Do("DEL", dest)
+		q.SetOp = nil
+	}
+
+	key, err := q.Key.Build()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	n := -1
+	if q.Limit >= 0 {
+		n = q.Offset + q.Limit
+	}
+	entries, err := s.selectTies(conn, key, cursor, q.Reverse, n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if n >= 0 && len(entries) == n {
+		return entries[q.Offset:], nil
+	}
+
+	skipped := q.Offset
+	if skipped > len(entries) {
+		skipped = len(entries)
+	}
+	entries = entries[skipped:]
+	q.Offset -= skipped
+	if q.Limit >= 0 {
+		q.Limit -= len(entries)
+	}
+	if q.Reverse {
+		q.Max = "(" + cursor.Score
+	} else {
+		q.Min = "(" + cursor.Score
+	}
+
+	values, err := redis.Strings(s.doQuery(conn, q))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(entries, toPageEntries(values)...), nil
+}
+
+// storeSetOp executes the command storing a set operation, and sets pageSetOpTTL to the stored set in a transaction.
+func storeSetOp(conn Conn, storeCmd *rq.Command) error {
+	dest := storeCmd.Args[0]
+	ttl := int64(pageSetOpTTL / time.Millisecond)
+
+	err := conn.Send("MULTI")
+	if err != nil {
+		return errors.Wrap(err, "faild to send MULTI command")
+	}
+	err = conn.Send(storeCmd.Name, storeCmd.Args...)
+	if err == nil {
+		err = conn.Send("PEXPIRE", dest, ttl)
+	}
+	if err != nil {
+		conn.Do("DISCARD")
+		return errors.Wrap(err, "faild to send any commands")
+	}
+
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return errors.Wrapf(err, "faild to execute %v", storeCmd)
+	}
+	for _, r := range replies {
+		if err, ok := r.(error); ok {
+			return errors.Wrapf(err, "faild to execute %v", storeCmd)
+		}
+	}
+	return nil
+}
+
+// selectTies selects at most n entries which have the same score as the cursor and follow its member (n < 0 means no limit).
+// Members with the same score are ordered lexicographically, so the position of the cursor is found by the rank of its member,
+// or by a binary search over members with the score when the member has been removed or rescored.
+func (s *redisStore) selectTies(conn Conn, key string, cursor *rq.Cursor, reverse bool, n int) ([]*pageEntry, error) {
+	cursorScore, err := strconv.ParseFloat(cursor.Score, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cursor score %q", cursor.Score)
+	}
+
+	rangeCmd, rankCmd := "ZRANGE", "ZRANK"
+	precedings := []interface{}{key, "-inf", "(" + cursor.Score}
+	follows := func(member string) bool { return member > cursor.Member }
+	if reverse {
+		rangeCmd, rankCmd = "ZREVRANGE", "ZREVRANK"
+		precedings = []interface{}{key, "(" + cursor.Score, "+inf"}
+		follows = func(member string) bool { return member < cursor.Member }
+	}
+
+	cmds := []*rq.Command{
+		{Name: "ZCOUNT", Args: precedings},
+		{Name: "ZCOUNT", Args: []interface{}{key, cursor.Score, cursor.Score}},
+		{Name: "ZSCORE", Args: []interface{}{key, cursor.Member}},
+		{Name: rankCmd, Args: []interface{}{key, cursor.Member}},
+	}
+	for _, cmd := range cmds {
+		err = conn.Send(cmd.Name, cmd.Args...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to send %v", cmd)
+		}
+	}
+	err = conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush commands locating the cursor")
+	}
+	replies := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		replies[i], err = conn.Receive()
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to execute %v", cmd)
+		}
+	}
+
+	first, err := redis.Int(replies[0], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to count members preceding the cursor")
+	}
+	cnt, err := redis.Int(replies[1], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to count members with the cursor score")
+	}
+	if cnt == 0 {
+		return nil, nil
+	}
+
+	var start int
+	memberScore, err := redis.Float64(replies[2], nil)
+	if err == nil && memberScore == cursorScore {
+		start, err = redis.Int(replies[3], nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to get a rank of %s", cursor.Member)
+		}
+		start++
+	} else if err != nil && err != redis.ErrNil {
+		return nil, errors.Wrapf(err, "faild to get a score of %s", cursor.Member)
+	} else {
+		var searchErr error
+		start = first + sort.Search(cnt, func(i int) bool {
+			if searchErr != nil {
+				return true
+			}
+			var members []string
+			members, searchErr = redis.Strings(conn.Do(rangeCmd, key, first+i, first+i))
+			return len(members) == 0 || follows(members[0])
+		})
+		if searchErr != nil {
+			return nil, errors.Wrap(searchErr, "faild to search the cursor position")
+		}
+	}
+
+	end := first + cnt - 1
+	if n >= 0 && start+n-1 < end {
+		end = start + n - 1
+	}
+	if start > end {
+		return nil, nil
+	}
+
+	values, err := redis.Strings(conn.Do(rangeCmd, key, start, end, "WITHSCORES"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "faild to execute %s %s %d %d", rangeCmd, key, start, end)
+	}
+
+	// ranks can be shifted by concurrent changes, so entries which do not follow the cursor are skipped
+	entries := make([]*pageEntry, 0, len(values)/2)
+	for _, e := range toPageEntries(values) {
+		score, err := strconv.ParseFloat(e.score, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to parse score of %s", e.member)
+		}
+		if score == cursorScore && follows(e.member) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// toPageEntries converts a reply with WITHSCORES into entries.
+func toPageEntries(values []string) []*pageEntry {
+	entries := make([]*pageEntry, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		entries = append(entries, &pageEntry{member: values[i], score: values[i+1]})
+	}
+	return entries
+}
@@ -0,0 +1,314 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestRedisStore_ListPage(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+
+	now := time.Now().UTC()
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano()},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * time.Hour).UnixNano()},
+		{ID: 3, Title: "post 3", UpdatedAt: now.UnixNano()},
+		{ID: 4, Title: "post 4", UpdatedAt: now.UnixNano()},
+		{ID: 5, Title: "post 5", UpdatedAt: now.Add(1 * time.Hour).UnixNano()},
+	}
+
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		mods  []rq.Modifier
+		pages [][]int
+	}{
+		{
+			name:  "recent",
+			mods:  []rq.Modifier{rq.Key("recent"), rq.Limit(2)},
+			pages: [][]int{{1, 0}, {2, 3}, {4}},
+		},
+		{
+			name:  "recent with reverse",
+			mods:  []rq.Modifier{rq.Key("recent"), rq.Limit(2), rq.Reverse()},
+			pages: [][]int{{4, 3}, {2, 0}, {1}},
+		},
+		{
+			name:  "recent with GtEq",
+			mods:  []rq.Modifier{rq.Key("recent"), rq.GtEq(now.UnixNano()), rq.Limit(3)},
+			pages: [][]int{{0, 2, 3}, {4}},
+		},
+		{
+			name:  "recent with limit of the number of posts",
+			mods:  []rq.Modifier{rq.Key("recent"), rq.Limit(5)},
+			pages: [][]int{{1, 0, 2, 3, 4}},
+		},
+		{
+			name:  "recent without limit",
+			mods:  []rq.Modifier{rq.Key("recent")},
+			pages: [][]int{{1, 0, 2, 3, 4}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var cursor string
+			for i, page := range c.pages {
+				gotPosts := []*rotesting.Post{}
+				next, err := store.ListPage(context.TODO(), &gotPosts, append(c.mods, rq.After(cursor))...)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				wantPosts := make([]*rotesting.Post, len(page))
+				for j, k := range page {
+					wantPosts[j] = posts[k]
+				}
+				if got, want := gotPosts, wantPosts; !reflect.DeepEqual(got, want) {
+					t.Errorf("ListPage() returned %v at page %d, want %v", got, i, want)
+				}
+
+				if last := i == len(c.pages)-1; last != (next == "") {
+					t.Errorf("ListPage() returned cursor %q at page %d", next, i)
+				}
+				cursor = next
+			}
+		})
+	}
+}
+
+func TestRedisStore_ListPage_Backward(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+
+	now := time.Now().UTC()
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano()},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * time.Hour).UnixNano()},
+		{ID: 3, Title: "post 3", UpdatedAt: now.UnixNano()},
+		{ID: 4, Title: "post 4", UpdatedAt: now.Add(1 * time.Hour).UnixNano()},
+	}
+
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts := []*rotesting.Post{}
+	next, err := store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Limit(3), rq.Before(""))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{posts[0], posts[2], posts[3]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPage() returned %v, want %v", got, want)
+	}
+
+	gotPosts = []*rotesting.Post{}
+	next, err = store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Limit(3), rq.Before(next))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{posts[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPage() returned %v, want %v", got, want)
+	}
+	if next != "" {
+		t.Errorf("ListPage() returned cursor %q, want empty", next)
+	}
+}
+
+func TestRedisStore_ListPage_WithConcurrentInsertion(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+
+	now := time.Now().UTC()
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano()},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * time.Hour).UnixNano()},
+		{ID: 3, Title: "post 3", UpdatedAt: now.Add(-2 * time.Hour).UnixNano()},
+		{ID: 4, Title: "post 4", UpdatedAt: now.Add(-3 * time.Hour).UnixNano()},
+	}
+
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts := []*rotesting.Post{}
+	next, err := store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Reverse(), rq.Limit(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{posts[0], posts[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPage() returned %v, want %v", got, want)
+	}
+
+	err = store.Put(context.TODO(), &rotesting.Post{ID: 5, Title: "post 5", UpdatedAt: now.Add(1 * time.Hour).UnixNano()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts = []*rotesting.Post{}
+	_, err = store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Reverse(), rq.Limit(2), rq.After(next))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{posts[2], posts[3]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPage() returned %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_ListPage_WithTies(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+
+	posts := make([]*rotesting.Post, 7)
+	for i := range posts {
+		posts[i] = &rotesting.Post{ID: uint64(i + 1), UpdatedAt: 100}
+	}
+	posts[6].UpdatedAt = 200
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		mods  []rq.Modifier
+		pages [][]int
+	}{
+		{
+			name:  "forward",
+			mods:  []rq.Modifier{rq.Key("recent"), rq.Limit(2)},
+			pages: [][]int{{0, 1}, {2, 3}, {4, 5}, {6}},
+		},
+		{
+			name:  "reverse",
+			mods:  []rq.Modifier{rq.Key("recent"), rq.Limit(3), rq.Reverse()},
+			pages: [][]int{{6, 5, 4}, {3, 2, 1}, {0}},
+		},
+		{
+			name:  "union",
+			mods:  []rq.Modifier{rq.Union(rq.SetKey{"recent"}, rq.SetKey{"id"}), rq.Weights(1, 0), rq.Limit(4)},
+			pages: [][]int{{0, 1, 2, 3}, {4, 5, 6}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var cursor string
+			for i, page := range c.pages {
+				gotPosts := []*rotesting.Post{}
+				next, err := store.ListPage(context.TODO(), &gotPosts, append(c.mods, rq.After(cursor))...)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				wantPosts := make([]*rotesting.Post, len(page))
+				for j, k := range page {
+					wantPosts[j] = posts[k]
+				}
+				if got, want := gotPosts, wantPosts; !reflect.DeepEqual(got, want) {
+					t.Errorf("ListPage() returned %v at page %d, want %v", got, i, want)
+				}
+				cursor = next
+			}
+			if cursor != "" {
+				t.Errorf("ListPage() returned cursor %q at the last page", cursor)
+			}
+		})
+	}
+
+	t.Run("after removing the cursor member", func(t *testing.T) {
+		gotPosts := []*rotesting.Post{}
+		next, err := store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Limit(3))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		err = store.Delete(context.TODO(), gotPosts[2])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		gotPosts = []*rotesting.Post{}
+		_, err = store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Limit(3), rq.After(next))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got, want := gotPosts, []*rotesting.Post{posts[3], posts[4], posts[5]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ListPage() returned %v, want %v", got, want)
+		}
+	})
+}
+
+func TestRedisStore_ListPage_WithLex(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	err := store.Put(context.TODO(), []*Author{
+		{ID: 1, Name: "bob"},
+		{ID: 2, Name: "alice"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []*Author
+	_, err = store.ListPage(context.TODO(), &got, rq.Key("name"), rq.LexPrefix("bob"), rq.Limit(1))
+	if !errors.Is(err, ro.ErrLexPage) {
+		t.Errorf("ListPage() returned %v, want ErrLexPage", err)
+	}
+}
+
+func TestRedisStore_WithCursorsOutsideListPage(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+	err := store.Put(context.TODO(), []*rotesting.Post{{ID: 1, UpdatedAt: 100}, {ID: 2, UpdatedAt: 200}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts := []*rotesting.Post{}
+	next, err := store.ListPage(context.TODO(), &gotPosts, rq.Key("recent"), rq.Limit(1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = store.List(context.TODO(), &gotPosts, rq.Key("recent"), rq.After(next))
+	if err == nil {
+		t.Error("List() with a cursor should return an error")
+	}
+	_, err = store.Count(context.TODO(), rq.Key("recent"), rq.Before(next))
+	if err == nil {
+		t.Error("Count() with a cursor should return an error")
+	}
+	err = store.DeleteAll(context.TODO(), rq.Key("recent"), rq.After(next))
+	if err == nil {
+		t.Error("DeleteAll() with a cursor should return an error")
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 2; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
@@ -2,10 +2,13 @@ package ro_test
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/gomodule/redigo/redis"
+
 	"github.com/izumin5210/ro"
 	"github.com/izumin5210/ro/rq"
 	rotesting "github.com/izumin5210/ro/testing"
@@ -197,3 +200,293 @@ func TestRedisStore_List(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisStore_List_WithValueSlice(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+
+	now := time.Now().UTC()
+	posts := []rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano()},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * 60 * 60 * time.Second).UnixNano()},
+	}
+
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts := []rotesting.Post{}
+	err = store.List(context.TODO(), &gotPosts, rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []rotesting.Post{posts[1], posts[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+}
+
+type Author struct {
+	ID   uint64 `redis:"id"`
+	Name string `redis:"name"`
+}
+
+func (a *Author) GetKeySuffix() string {
+	return fmt.Sprint(a.ID)
+}
+
+func (a *Author) GetScoreMap() map[string]interface{} {
+	return map[string]interface{}{"id": a.ID}
+}
+
+func (a *Author) GetLexMap() map[string]string {
+	return map[string]string{"name": a.Name}
+}
+
+func TestRedisStore_List_WithLex(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	authors := []*Author{
+		{ID: 1, Name: "bob"},
+		{ID: 2, Name: "alice"},
+		{ID: 3, Name: "bobby"},
+		{ID: 4, Name: "carol"},
+		{ID: 5, Name: "bob"},
+	}
+	err := store.Put(context.TODO(), authors)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		mods []rq.Modifier
+		want []int
+	}{
+		{
+			name: "all",
+			mods: []rq.Modifier{rq.Key("name")},
+			want: []int{1, 0, 4, 2, 3},
+		},
+		{
+			name: "with LexPrefix",
+			mods: []rq.Modifier{rq.Key("name"), rq.LexPrefix("bob")},
+			want: []int{0, 4, 2},
+		},
+		{
+			name: "with LexEq",
+			mods: []rq.Modifier{rq.Key("name"), rq.LexEq("bob")},
+			want: []int{0, 4},
+		},
+		{
+			name: "with LexGt",
+			mods: []rq.Modifier{rq.Key("name"), rq.LexGt("bob")},
+			want: []int{2, 3},
+		},
+		{
+			name: "with LexLtEq",
+			mods: []rq.Modifier{rq.Key("name"), rq.LexLtEq("bob")},
+			want: []int{1, 0, 4},
+		},
+		{
+			name: "with LexLt and reverse",
+			mods: []rq.Modifier{rq.Key("name"), rq.LexLt("bobby"), rq.Reverse()},
+			want: []int{4, 0, 1},
+		},
+		{
+			name: "with LexGtEq and limit",
+			mods: []rq.Modifier{rq.Key("name"), rq.LexGtEq("bob"), rq.Limit(2)},
+			want: []int{0, 4},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := []*Author{}
+			err := store.List(context.TODO(), &got, c.mods...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			want := make([]*Author, len(c.want))
+			for i, j := range c.want {
+				want[i] = authors[j]
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("List() returned %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRedisStore_List_WithLex_WhenValueChanged(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	err := store.Put(context.TODO(), &Author{ID: 1, Name: "bob"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	author := &Author{ID: 1, Name: "alice"}
+	err = store.Put(context.TODO(), author)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := []*Author{}
+	err = store.List(context.TODO(), &got, rq.Key("name"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []*Author{author}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	got = []*Author{}
+	err = store.List(context.TODO(), &got, rq.Key("name"), rq.LexEq("bob"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("List() returned %v, want empty", got)
+	}
+}
+
+func TestRedisStore_List_WithSetOp(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Post{})
+	posts := []*Post{
+		{ID: 1, UserID: 1, Title: "post 1", CreatedAt: 3},
+		{ID: 2, UserID: 2, Title: "post 2", CreatedAt: 1},
+		{ID: 3, UserID: 3, Title: "post 3", CreatedAt: 2},
+		{ID: 4, UserID: 1, Title: "post 4", CreatedAt: 4},
+		{ID: 5, UserID: 2, Title: "post 5", CreatedAt: 5},
+	}
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		mods  []rq.Modifier
+		order []int
+	}{
+		{
+			name:  "union",
+			mods:  []rq.Modifier{rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2})},
+			order: []int{1, 0, 3, 4},
+		},
+		{
+			name:  "union with reverse and limit",
+			mods:  []rq.Modifier{rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2}), rq.Reverse(), rq.Limit(3)},
+			order: []int{4, 3, 0},
+		},
+		{
+			name:  "intersection with weights",
+			mods:  []rq.Modifier{rq.Inter(rq.SetKey{"user", 1}, rq.SetKey{"recent"}), rq.Weights(1, 0), rq.GtEq(4)},
+			order: []int{3},
+		},
+		{
+			name:  "intersection with aggregate",
+			mods:  []rq.Modifier{rq.Inter(rq.SetKey{"user", 2}, rq.SetKey{"recent"}), rq.AggregateBy(rq.AggregateMin), rq.Reverse()},
+			order: []int{4, 1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := []*Post{}
+			err := store.List(context.TODO(), &got, c.mods...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			want := make([]*Post, len(c.order))
+			for i, j := range c.order {
+				want[i] = posts[j]
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("List() returned %v, want %v", got, want)
+			}
+		})
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "Post#tmp*"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Temporary keys %v are left", keys)
+	}
+
+	err = store.List(context.TODO(), &[]*Post{}, rq.Key("recent"), rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2}))
+	if err == nil {
+		t.Error("List() should return an error when a key is specified with a set operation")
+	}
+
+	cnt, err := redis.Int(conn.Do("ZCARD", "Post/recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, len(posts); got != want {
+		t.Errorf("Post/recent has %d members, want %d", got, want)
+	}
+}
+
+func TestRedisStore_List_WithSelect(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", Body: "This is a post 1.", UpdatedAt: 2},
+		{ID: 2, Title: "post 2", Body: "This is a post 2.", UpdatedAt: 1},
+	}
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := []*rotesting.Post{}
+	err = store.List(context.TODO(), &got, rq.Key("recent"), rq.Select("id", "title"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []*rotesting.Post{{ID: 2, Title: "post 2"}, {ID: 1, Title: "post 1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_List_WhenHashIsMissing(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: 1},
+		{ID: 2, Title: "post 2", UpdatedAt: 2},
+	}
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+	conn.Do("DEL", "Post:1")
+
+	got := []*rotesting.Post{}
+	err = store.List(context.TODO(), &got, rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []*rotesting.Post{posts[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+}
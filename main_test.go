@@ -11,7 +11,7 @@ import (
 var pool *rotesting.Pool
 
 func TestMain(m *testing.M) {
-	pool = rotesting.MustCreate()
+	pool = rotesting.MustCreateLocal()
 
 	code := m.Run()
 
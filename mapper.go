@@ -0,0 +1,424 @@
+package ro
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// nestedFieldDelimiter joins names of nested struct fields and their parents in hash field names.
+const nestedFieldDelimiter = "."
+
+// structMapping describes how fields of a struct type are stored in hash fields.
+// Fields follow `redis` struct tags, which are compatible with redis.AddFlat and redis.ScanStruct.
+type structMapping struct {
+	fields []*fieldMapping
+}
+
+// fieldMapping is a mapping of a struct field.
+// A field of a nested struct has a nested mapping, whose fields are stored with a path prefix.
+// Fields of embedded structs are stored without prefixes.
+type fieldMapping struct {
+	index     int
+	name      string
+	omitEmpty bool
+	ptr       bool
+	typ       reflect.Type
+	nested    *structMapping
+}
+
+var (
+	mappingCache      sync.Map
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// getMapping returns a cached mapping of the struct type.
+func getMapping(t reflect.Type) *structMapping {
+	if v, ok := mappingCache.Load(t); ok {
+		return v.(*structMapping)
+	}
+	v, _ := mappingCache.LoadOrStore(t, compileMapping(t, "", map[reflect.Type]bool{}))
+	return v.(*structMapping)
+}
+
+// compileMapping compiles a mapping of the struct type.
+// compiling holds types of the struct and its ancestors, and fields referring to them are stored as JSON instead of being nested,
+// since recursive types would be nested endlessly.
+func compileMapping(t reflect.Type, prefix string, compiling map[reflect.Type]bool) *structMapping {
+	compiling[t] = true
+	defer delete(compiling, t)
+
+	m := &structMapping{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tokens := strings.Split(f.Tag.Get("redis"), ",")
+		name := tokens[0]
+		if name == "-" {
+			continue
+		}
+
+		fm := &fieldMapping{index: i, typ: f.Type}
+		for _, opt := range tokens[1:] {
+			if opt == "omitempty" {
+				fm.omitEmpty = true
+			}
+		}
+		if fm.typ.Kind() == reflect.Ptr {
+			fm.ptr = true
+			fm.typ = fm.typ.Elem()
+		}
+
+		switch {
+		case fm.typ.Kind() == reflect.Interface, fm.typ.Kind() == reflect.Func, fm.typ.Kind() == reflect.Chan:
+			continue
+		case isNestedStruct(fm.typ) && !compiling[fm.typ]:
+			if f.Anonymous && name == "" {
+				fm.nested = compileMapping(fm.typ, prefix, compiling)
+			} else {
+				if name == "" {
+					name = f.Name
+				}
+				fm.nested = compileMapping(fm.typ, prefix+name+nestedFieldDelimiter, compiling)
+			}
+		default:
+			if f.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fm.name = prefix + name
+		}
+		m.fields = append(m.fields, fm)
+	}
+	return m
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// lookup returns a mapping of a field with the index, which is an index sequence like reflect.StructField.Index.
+func (m *structMapping) lookup(index []int) *fieldMapping {
+	for _, fm := range m.fields {
+		if fm.index != index[0] {
+			continue
+		}
+		if len(index) == 1 {
+			return fm
+		}
+		if fm.nested == nil {
+			return nil
+		}
+		return fm.nested.lookup(index[1:])
+	}
+	return nil
+}
+
+// names returns names of hash fields storing the field.
+func (fm *fieldMapping) names() []string {
+	if fm.nested == nil {
+		return []string{fm.name}
+	}
+	var names []string
+	for _, f := range fm.nested.fields {
+		names = append(names, f.names()...)
+	}
+	return names
+}
+
+// hasName reports whether the mapping has a hash field with the name.
+func (m *structMapping) hasName(name string) bool {
+	for _, fm := range m.fields {
+		for _, n := range fm.names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toHash returns field-value pairs of the struct, and names of fields which should be removed since they are nil or omitted.
+func toHash(v interface{}) ([]interface{}, []string, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var pairs []interface{}
+	var nulls []string
+	for _, fm := range getMapping(rv.Type()).fields {
+		var err error
+		pairs, nulls, err = fm.appendHash(pairs, nulls, rv)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+	return pairs, nulls, nil
+}
+
+// toHashFields returns field-value pairs of the field with the index, and names of fields which should be removed since they are nil or omitted.
+func toHashFields(v interface{}, index []int) ([]interface{}, []string, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	fm := getMapping(rv.Type()).lookup(index)
+	if fm == nil {
+		return nil, nil, errors.Errorf("%s.%v is not stored", rv.Type().Name(), index)
+	}
+	parent := rv
+	if len(index) > 1 {
+		parent = rv.FieldByIndex(index[:len(index)-1])
+	}
+	pairs, nulls, err := fm.appendHash(nil, nil, parent)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return pairs, nulls, nil
+}
+
+func (fm *fieldMapping) appendHash(pairs []interface{}, nulls []string, parent reflect.Value) ([]interface{}, []string, error) {
+	fv := parent.Field(fm.index)
+	if fm.ptr {
+		if fv.IsNil() {
+			return pairs, append(nulls, fm.names()...), nil
+		}
+		fv = fv.Elem()
+	}
+
+	if fm.nested != nil {
+		for _, f := range fm.nested.fields {
+			var err error
+			pairs, nulls, err = f.appendHash(pairs, nulls, fv)
+			if err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+		}
+		return pairs, nulls, nil
+	}
+
+	if fm.omitEmpty && !fm.ptr && isEmptyValue(fv) {
+		// omitted fields are removed as nil pointers, so that stale values are not left
+		return pairs, append(nulls, fm.name), nil
+	}
+
+	data, err := encodeValue(fv)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to encode %s", fm.name)
+	}
+	return append(pairs, fm.name, data), nulls, nil
+}
+
+// scanHash scans field-value pairs of a hash into the struct pointer.
+// Fields missing in the hash are left untouched.
+func scanHash(pairs []interface{}, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("cannot scan into %T", dest)
+	}
+	rv = rv.Elem()
+
+	values := make(map[string][]byte, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		name, err := toBytes(pairs[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		data, err := toBytes(pairs[i+1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		values[string(name)] = data
+	}
+
+	for _, fm := range getMapping(rv.Type()).fields {
+		err := fm.scan(values, rv)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (fm *fieldMapping) scan(values map[string][]byte, parent reflect.Value) error {
+	fv := parent.Field(fm.index)
+
+	if fm.nested != nil {
+		if fm.ptr {
+			if !fm.hasAnyValue(values) {
+				return nil
+			}
+			if fv.IsNil() {
+				fv.Set(reflect.New(fm.typ))
+			}
+			fv = fv.Elem()
+		}
+		for _, f := range fm.nested.fields {
+			err := f.scan(values, fv)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+
+	data, ok := values[fm.name]
+	if !ok {
+		return nil
+	}
+	if fm.ptr {
+		v := reflect.New(fm.typ)
+		fv.Set(v)
+		fv = v.Elem()
+	}
+	err := decodeValue(fv, data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode %s %q", fm.name, data)
+	}
+	return nil
+}
+
+func (fm *fieldMapping) hasAnyValue(values map[string][]byte) bool {
+	for _, n := range fm.names() {
+		if _, ok := values[n]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeValue encodes scalar values in the same way as redigo, and the other values as text or JSON.
+func encodeValue(v reflect.Value) ([]byte, error) {
+	if v.Type() != timeType && reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		// MarshalText can have a pointer receiver as well as UnmarshalText
+		if !v.CanAddr() {
+			pv := reflect.New(v.Type())
+			pv.Elem().Set(v)
+			v = pv.Elem()
+		}
+		return v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return []byte(v.String()), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(nil, v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, v.Float(), 'g', -1, 64), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).MarshalText()
+		}
+	}
+
+	return json.Marshal(v.Interface())
+}
+
+func decodeValue(v reflect.Value, data []byte) error {
+	if v.Type() != timeType && reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText(data)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(data))
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(string(data))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(data), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(string(data), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(string(data), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), data...))
+			return nil
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			var t time.Time
+			err := t.UnmarshalText(data)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
+
+func toBytes(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unexpected hash element type %T", v)
+	}
+}
@@ -0,0 +1,271 @@
+package ro_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+)
+
+type Timestamps struct {
+	CreatedAt time.Time `redis:"created_at"`
+}
+
+type ProfileAuthor struct {
+	Name string `redis:"name"`
+	Age  *int64 `redis:"age"`
+}
+
+type Profile struct {
+	Timestamps
+	ID       uint64        `redis:"id" ro:"key;score=id"`
+	Nickname *string       `redis:"nickname"`
+	Author   ProfileAuthor `redis:"author"`
+	Tags     []string      `redis:"tags"`
+}
+
+func TestRedisStore_WithRichFields(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &Profile{})
+	nickname, age := "foo", int64(20)
+	createdAt := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	profile := &Profile{
+		Timestamps: Timestamps{CreatedAt: createdAt},
+		ID:         1,
+		Nickname:   &nickname,
+		Author:     ProfileAuthor{Name: "bar", Age: &age},
+		Tags:       []string{"a", "b"},
+	}
+
+	err := store.Put(context.TODO(), profile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	t.Run("stored fields", func(t *testing.T) {
+		got, err := redis.StringMap(conn.Do("HGETALL", "Profile:1"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := map[string]string{
+			"created_at":  "2017-10-01T12:00:00Z",
+			"id":          "1",
+			"nickname":    "foo",
+			"author.name": "bar",
+			"author.age":  "20",
+			"tags":        `["a","b"]`,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored hash is %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		got := &Profile{ID: 1}
+		err := store.Get(context.TODO(), got)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := profile; !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored profile is %v, want %v", got, want)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		got := []*Profile{}
+		err := store.List(context.TODO(), &got, rq.Key("id"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := []*Profile{profile}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored profiles are %v, want %v", got, want)
+		}
+	})
+
+	t.Run("GetFields with nested fields", func(t *testing.T) {
+		got := &Profile{ID: 1}
+		err := store.GetFields(context.TODO(), []string{"Author"}, got)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := (&Profile{ID: 1, Author: profile.Author}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored profile is %v, want %v", got, want)
+		}
+
+		got = &Profile{ID: 1}
+		err = store.GetFields(context.TODO(), []string{"author.name"}, got)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := (&Profile{ID: 1, Author: ProfileAuthor{Name: "bar"}}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored profile is %v, want %v", got, want)
+		}
+	})
+
+	t.Run("nil pointers", func(t *testing.T) {
+		err := store.Update(context.TODO(), &Profile{ID: 1}, "Nickname", "Author")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		got := &Profile{ID: 1}
+		err = store.Get(context.TODO(), got)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := &Profile{
+			Timestamps: profile.Timestamps,
+			ID:         1,
+			Tags:       profile.Tags,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Stored profile is %v, want %v", got, want)
+		}
+
+		exists, err := redis.Bool(conn.Do("HEXISTS", "Profile:1", "nickname"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if exists {
+			t.Error("nickname should be removed from the hash")
+		}
+	})
+}
+
+type Color struct {
+	R, G, B uint8
+}
+
+func (c *Color) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)), nil
+}
+
+func (c *Color) UnmarshalText(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	return err
+}
+
+type Palette struct {
+	ID   uint64 `redis:"id" ro:"key"`
+	Main Color  `redis:"main"`
+	Sub  *Color `redis:"sub"`
+}
+
+func TestRedisStore_WithTextMarshalers(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &Palette{})
+	palette := &Palette{ID: 1, Main: Color{R: 255}, Sub: &Color{G: 128, B: 255}}
+
+	err := store.Put(context.TODO(), palette)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	stored, err := redis.StringMap(conn.Do("HGETALL", "Palette:1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := map[string]string{"id": "1", "main": "#ff0000", "sub": "#0080ff"}; !reflect.DeepEqual(stored, want) {
+		t.Errorf("Stored hash is %v, want %v", stored, want)
+	}
+
+	got := &Palette{ID: 1}
+	err = store.Get(context.TODO(), got)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := palette; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored palette is %v, want %v", got, want)
+	}
+}
+
+type Category struct {
+	ID   uint64    `redis:"id" ro:"key"`
+	Name string    `redis:"name"`
+	Next *Category `redis:"next"`
+}
+
+func TestRedisStore_WithRecursiveFields(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &Category{})
+	category := &Category{ID: 1, Name: "books", Next: &Category{ID: 2, Name: "comics"}}
+
+	err := store.Put(context.TODO(), category)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	stored, err := redis.StringMap(conn.Do("HGETALL", "Category:1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{"id": "1", "name": "books", "next": `{"ID":2,"Name":"comics","Next":null}`}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("Stored hash is %v, want %v", stored, want)
+	}
+
+	got := &Category{ID: 1}
+	err = store.Get(context.TODO(), got)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := category; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored category is %v, want %v", got, want)
+	}
+}
+
+type Bookmark struct {
+	ID    uint64  `redis:"id" ro:"key"`
+	Title string  `redis:"title,omitempty"`
+	Note  *string `redis:"note,omitempty"`
+}
+
+func TestRedisStore_WithOmitEmpty(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &Bookmark{})
+	note := "read later"
+
+	err := store.Put(context.TODO(), &Bookmark{ID: 1, Title: "ro", Note: &note})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = store.Put(context.TODO(), &Bookmark{ID: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := &Bookmark{ID: 1}
+	err = store.Get(context.TODO(), got)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := (&Bookmark{ID: 1}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored bookmark is %v, want %v", got, want)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	stored, err := redis.StringMap(conn.Do("HGETALL", "Bookmark:1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := map[string]string{"id": "1"}; !reflect.DeepEqual(stored, want) {
+		t.Errorf("Stored hash is %v, want %v", stored, want)
+	}
+}
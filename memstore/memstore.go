@@ -0,0 +1,17 @@
+// Package memstore provides stores keeping models in memory.
+// They run the same store implementation on miniredis in process, so unit tests can run without redis servers.
+package memstore
+
+import (
+	"github.com/izumin5210/ro"
+)
+
+// New creates a store keeping models in memory.
+func New(model interface{}, opts ...ro.Option) ro.Store {
+	return ro.New(NewPool(), model, opts...)
+}
+
+// NewTyped creates a type-safe store keeping models in memory.
+func NewTyped[T any](opts ...ro.Option) ro.TypedStore[T] {
+	return ro.NewTyped[T](NewPool(), opts...)
+}
@@ -0,0 +1,250 @@
+package memstore_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/memstore"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New(&rotesting.Post{})
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", Body: "This is a post 1.", UpdatedAt: 300},
+		{ID: 2, Title: "post 2", Body: "This is a post 2.", UpdatedAt: 100},
+		{ID: 3, Title: "post 3", Body: "This is a post 3.", UpdatedAt: 200},
+		{ID: 4, Title: "post 4", Body: "This is a post 4.", UpdatedAt: 200},
+	}
+
+	err := store.Put(ctx, posts)
+	if err != nil {
+		t.Fatalf("Put returns an error: %v", err)
+	}
+
+	listCases := []struct {
+		name string
+		mods []rq.Modifier
+		want []*rotesting.Post
+	}{
+		{
+			name: "all",
+			mods: []rq.Modifier{rq.Key("recent")},
+			want: []*rotesting.Post{posts[1], posts[2], posts[3], posts[0]},
+		},
+		{
+			name: "reverse",
+			mods: []rq.Modifier{rq.Key("recent"), rq.Reverse()},
+			want: []*rotesting.Post{posts[0], posts[3], posts[2], posts[1]},
+		},
+		{
+			name: "limit and offset",
+			mods: []rq.Modifier{rq.Key("recent"), rq.Offset(1), rq.Limit(2)},
+			want: []*rotesting.Post{posts[2], posts[3]},
+		},
+		{
+			name: "score range",
+			mods: []rq.Modifier{rq.Key("recent"), rq.Gt(100), rq.LtEq(200)},
+			want: []*rotesting.Post{posts[2], posts[3]},
+		},
+		{
+			name: "score range with reverse and limit",
+			mods: []rq.Modifier{rq.Key("recent"), rq.GtEq(200), rq.Reverse(), rq.Limit(2)},
+			want: []*rotesting.Post{posts[0], posts[3]},
+		},
+		{
+			name: "union",
+			mods: []rq.Modifier{rq.Union(rq.SetKey{"id"}, rq.SetKey{"recent"}), rq.Weights(0, 1), rq.Lt(300)},
+			want: []*rotesting.Post{posts[1], posts[2], posts[3]},
+		},
+	}
+
+	for _, c := range listCases {
+		t.Run("List with "+c.name, func(t *testing.T) {
+			got := []*rotesting.Post{}
+			err := store.List(ctx, &got, c.mods...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("List returns %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	t.Run("ListPage", func(t *testing.T) {
+		var got []*rotesting.Post
+		var cursor string
+		for {
+			page := []*rotesting.Post{}
+			next, err := store.ListPage(ctx, &page, rq.Key("recent"), rq.Limit(3), rq.After(cursor))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got = append(got, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if want := listCases[0].want; !reflect.DeepEqual(got, want) {
+			t.Errorf("ListPage returns %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		cnt, err := store.Count(ctx, rq.Key("recent"), rq.GtEq(200))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got, want := cnt, 3; got != want {
+			t.Errorf("Count returns %d, want %d", got, want)
+		}
+	})
+
+	t.Run("Get missing", func(t *testing.T) {
+		err := store.Get(ctx, &rotesting.Post{ID: 5})
+		if !errors.Is(err, ro.ErrNotFound) {
+			t.Errorf("Get returns %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DeleteAll", func(t *testing.T) {
+		err := store.DeleteAll(ctx, rq.Key("recent"), rq.Lt(200))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := []*rotesting.Post{}
+		err = store.List(ctx, &got, rq.Key("id"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := []*rotesting.Post{posts[0], posts[2], posts[3]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("List returns %v, want %v", got, want)
+		}
+	})
+}
+
+func TestStore_WithTTL(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewTyped[*rotesting.Post](ro.WithTTL(50 * time.Millisecond))
+
+	err := store.Put(ctx, &rotesting.Post{ID: 1, UpdatedAt: 100})
+	if err != nil {
+		t.Fatalf("Put returns an error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = store.Get(ctx, "1")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Get returns %v, want ErrNotFound", err)
+	}
+	n, err := store.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep returns an error: %v", err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("Sweep removes %d models, want %d", got, want)
+	}
+}
+
+func TestStore_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := memstore.NewTyped[*rotesting.Post](ro.WithChangeFeed(true), ro.WithChangeFeedPayload(true))
+
+	ch, err := store.Watch(ctx, "")
+	if err != nil {
+		t.Fatalf("Watch returns an error: %v", err)
+	}
+
+	post := &rotesting.Post{ID: 1, Title: "post 1", UpdatedAt: 100}
+	err = store.Put(ctx, post, &rotesting.Post{ID: 2, UpdatedAt: 200})
+	if err != nil {
+		t.Fatalf("Put returns an error: %v", err)
+	}
+	err = store.Delete(ctx, post)
+	if err != nil {
+		t.Fatalf("Delete returns an error: %v", err)
+	}
+	err = store.DeleteAll(ctx, rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("DeleteAll returns an error: %v", err)
+	}
+
+	wants := []struct {
+		op     ro.ChangeOp
+		suffix string
+	}{
+		{op: ro.ChangePut, suffix: "1"},
+		{op: ro.ChangePut, suffix: "2"},
+		{op: ro.ChangeDelete, suffix: "1"},
+		{op: ro.ChangeDelete, suffix: "2"},
+	}
+	for _, want := range wants {
+		select {
+		case e := <-ch:
+			if e.Err != nil {
+				t.Fatalf("Watch sends an error: %v", e.Err)
+			}
+			if e.Op != want.op || e.Suffix != want.suffix {
+				t.Errorf("Watch sends %s %s, want %s %s", e.Op, e.Suffix, want.op, want.suffix)
+			}
+			if e.Op == ro.ChangePut && e.Suffix == "1" && !reflect.DeepEqual(e.Model, post) {
+				t.Errorf("Watch sends %+v, want %+v", e.Model, post)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Watch does not send %s %s", want.op, want.suffix)
+		}
+	}
+}
+
+func TestStore_Verify(t *testing.T) {
+	ctx := context.Background()
+	pool := memstore.NewPool()
+	store := ro.New(pool, &rotesting.Post{})
+
+	err := store.Put(ctx, []*rotesting.Post{{ID: 1, UpdatedAt: 100}, {ID: 2, UpdatedAt: 200}})
+	if err != nil {
+		t.Fatalf("Put returns an error: %v", err)
+	}
+
+	conn, _ := pool.GetContext(ctx)
+	defer conn.Close()
+	conn.Do("DEL", "Post:1")
+	conn.Do("ZREM", "Post/recent", "Post:2")
+
+	report, err := store.Verify(ctx, ro.VerifyOptions{BatchSize: 1, Repair: true})
+	if err != nil {
+		t.Fatalf("Verify returns an error: %v", err)
+	}
+	if got, want := len(report.OrphanedMembers), 2; got != want {
+		t.Errorf("Verify reports %d orphaned members, want %d", got, want)
+	}
+	if got, want := report.MissingMembers, []ro.IndexMember{{SetKey: "Post/recent", Key: "Post:2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Verify reports missing members %v, want %v", got, want)
+	}
+
+	n, err := store.Reindex(ctx, ro.ReindexOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Reindex returns an error: %v", err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("Reindex reindexes %d models, want %d", got, want)
+	}
+
+	report, err = store.Verify(ctx, ro.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Verify returns an error: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("Verify reports %+v after repairing", report)
+	}
+}
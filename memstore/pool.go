@@ -0,0 +1,98 @@
+package memstore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/ro"
+)
+
+// server is a miniredis server shared by pools, and each pool uses its own database.
+var server struct {
+	once sync.Once
+	m    *miniredis.Miniredis
+	err  error
+
+	lastDB int32
+
+	// miniredis decreases TTLs only when it is fast-forwarded, so time passed since the last command is forwarded before each command
+	mu        sync.Mutex
+	forwarded time.Time
+}
+
+func startServer() (*miniredis.Miniredis, error) {
+	server.once.Do(func() {
+		m := miniredis.NewMiniRedis()
+		server.err = m.Start()
+		if server.err == nil {
+			server.m = m
+			server.forwarded = time.Now()
+		}
+	})
+	return server.m, errors.Wrap(server.err, "failed to start miniredis")
+}
+
+func forward() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	now := time.Now()
+	if d := now.Sub(server.forwarded); d > 0 {
+		server.m.FastForward(d)
+		server.forwarded = now
+	}
+}
+
+type pool struct {
+	pool *redis.Pool
+	err  error
+}
+
+// NewPool creates ro.Pool which serves redis commands in process with miniredis.
+// Each pool has its own database, and TTLs of keys elapse in real time.
+func NewPool() ro.Pool {
+	m, err := startServer()
+	if err != nil {
+		return &pool{err: err}
+	}
+	db := int(atomic.AddInt32(&server.lastDB, 1))
+	return &pool{
+		pool: &redis.Pool{
+			MaxIdle: 8,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", m.Addr(), redis.DialDatabase(db))
+			},
+		},
+	}
+}
+
+// GetContext implements the ro.Pool interface.
+func (p *pool) GetContext(ctx context.Context) (ro.Conn, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	c, err := p.pool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &conn{Conn: c}, nil
+}
+
+type conn struct {
+	redis.Conn
+}
+
+func (c *conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	forward()
+	return c.Conn.Do(commandName, args...)
+}
+
+func (c *conn) Send(commandName string, args ...interface{}) error {
+	forward()
+	return c.Conn.Send(commandName, args...)
+}
@@ -1,7 +1,24 @@
 package ro
 
+import (
+	"time"
+)
+
 // Model is an interface for redis objects
 type Model interface {
 	GetKeySuffix() string
 	GetScoreMap() map[string]interface{}
 }
+
+// Expirer is an optional interface for models which have limited lifetimes.
+// A TTL returned by GetTTL takes priority over the store's TTL, and a non-positive TTL means the model never expires.
+type Expirer interface {
+	GetTTL() time.Duration
+}
+
+// LexIndexer is an optional interface for models indexed in lexicographical order.
+// GetLexMap returns values keyed by names of lex sets, which can be queried with lexicographical ranges (e.g. rq.LexPrefix).
+// Names of lex sets share a namespace with keys of GetScoreMap.
+type LexIndexer interface {
+	GetLexMap() map[string]string
+}
@@ -0,0 +1,313 @@
+package ro
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// NearCache is an in-process LRU cache of models read by Get and List of stores created with WithNearCache.
+// Cached models are invalidated by changes recorded in change feeds of the stores, so changes made by other processes are reflected as well.
+// Stores read models from redis without caching while their change feeds cannot be watched.
+type NearCache struct {
+	size int
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	gen      uint64
+	stats    NearCacheStats
+	watchers map[string]*nearCacheWatcher
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NearCacheStats contains statistics of a near cache.
+// Misses include reads bypassing the cache, and Invalidations count cached models removed by changes.
+type NearCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Len           int
+}
+
+type nearCacheEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// nearCacheWatcher watches a change feed of stores.
+type nearCacheWatcher struct {
+	mu      sync.Mutex
+	running int32
+}
+
+// NewNearCache creates a NearCache holding at most size models.
+// Cached models are read again after ttl even if they are not invalidated, which bounds staleness when changes are delivered late (0 means no bound).
+// Models which can expire are not cached beyond their expiry.
+func NewNearCache(size int, ttl time.Duration) *NearCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &NearCache{
+		size:     size,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		watchers: make(map[string]*nearCacheWatcher),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Stats returns statistics of the cache.
+func (c *NearCache) Stats() NearCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
+
+// Close stops watching change feeds and removes all cached models.
+// Stores read models from redis without caching after the cache is closed.
+func (c *NearCache) Close() error {
+	c.cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	return nil
+}
+
+// read reads values stored with keys from the cache, and reads missing ones from redis with the store.
+func (c *NearCache) read(s *redisStore, conn Conn, keys []string) ([]interface{}, []bool, error) {
+	values := make([]interface{}, len(keys))
+	found := make([]bool, len(keys))
+	var missing []string
+	var missingIdx []int
+
+	c.mu.Lock()
+	gen := c.gen
+	now := time.Now()
+	for i, k := range keys {
+		if v, ok := c.get(k, now); ok {
+			values[i], found[i] = v, true
+			c.stats.Hits++
+		} else {
+			missing, missingIdx = append(missing, k), append(missingIdx, i)
+			c.stats.Misses++
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return values, found, nil
+	}
+
+	// the generation is taken before checking the watcher, so values read while it stops are not cached
+	cacheable := c.watch(s)
+
+	// expiry of models is not recorded in change feeds, so cached values expire with models
+	// TTLs are read before values, so cached values never outlive models even if they are put again in between
+	var expireAts []time.Time
+	if cacheable && s.isExpirable() {
+		var err error
+		expireAts, err = readExpireAts(conn, missing, now)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	fetched, fetchedFound, err := s.fetchValues(conn, missing, nil)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	for j, i := range missingIdx {
+		values[i], found[i] = fetched[j], fetchedFound[j]
+	}
+
+	if cacheable {
+		c.mu.Lock()
+		// values may be stale when keys were invalidated during reading them
+		if c.gen == gen {
+			for j, k := range missing {
+				if fetchedFound[j] {
+					var expireAt time.Time
+					if expireAts != nil {
+						expireAt = expireAts[j]
+					}
+					c.add(k, fetched[j], now, expireAt)
+				}
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	return values, found, nil
+}
+
+func (c *NearCache) get(key string, now time.Time) (interface{}, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*nearCacheEntry)
+	if !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e.value, true
+}
+
+// add caches the value until the TTL of the cache passes or the model expires at expireAt (zero means never).
+func (c *NearCache) add(key string, value interface{}, now, expireAt time.Time) {
+	if c.size <= 0 {
+		return
+	}
+	e := &nearCacheEntry{key: key, value: value, expiresAt: expireAt}
+	if c.ttl > 0 && (e.expiresAt.IsZero() || now.Add(c.ttl).Before(e.expiresAt)) {
+		e.expiresAt = now.Add(c.ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*nearCacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+// readExpireAts reads TTLs of keys, and returns times when they expire (zero means never).
+// Missing keys are treated as keys expiring now.
+func readExpireAts(conn Conn, keys []string, now time.Time) ([]time.Time, error) {
+	for _, k := range keys {
+		err := conn.Send("PTTL", k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to send PTTL %s", k)
+		}
+	}
+	err := conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush PTTL commands")
+	}
+
+	expireAts := make([]time.Time, len(keys))
+	for i, k := range keys {
+		ttl, err := redis.Int64(conn.Receive())
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to execute PTTL %s", k)
+		}
+		switch {
+		case ttl == -2:
+			expireAts[i] = now
+		case ttl >= 0:
+			expireAts[i] = now.Add(time.Duration(ttl) * time.Millisecond)
+		}
+	}
+	return expireAts, nil
+}
+
+// invalidate removes cached values of keys.
+func (c *NearCache) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, k := range keys {
+		if el, ok := c.entries[k]; ok {
+			c.lru.Remove(el)
+			delete(c.entries, k)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+// invalidatePrefix removes cached values of keys with the prefix.
+func (c *NearCache) invalidatePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for k, el := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			c.lru.Remove(el)
+			delete(c.entries, k)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+// watch starts watching the change feed of the store unless it is being watched, and reports whether it is being watched.
+func (c *NearCache) watch(s *redisStore) bool {
+	// watchers may be still running for a while after the cache is closed
+	if c.ctx.Err() != nil {
+		return false
+	}
+
+	changesKey := s.getChangesKey()
+	c.mu.Lock()
+	w, ok := c.watchers[changesKey]
+	if !ok {
+		w = &nearCacheWatcher{}
+		c.watchers[changesKey] = w
+	}
+	c.mu.Unlock()
+
+	if atomic.LoadInt32(&w.running) == 1 {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if atomic.LoadInt32(&w.running) == 1 {
+		return true
+	}
+
+	// the watcher reads changes while the cache is open, so its idle round trips are not reported to observers
+	ch, err := watchChanges(c.ctx, s, "", false, func(reflect.Value) struct{} { return struct{}{} })
+	if err != nil {
+		return false
+	}
+	atomic.StoreInt32(&w.running, 1)
+	go c.consume(s, w, ch)
+	return true
+}
+
+// consume invalidates cached values of changed models until the channel is closed.
+// Changes made while the feed is not watched are unknown, so all values of the store are removed after that.
+func (c *NearCache) consume(s *redisStore, w *nearCacheWatcher, ch <-chan *ChangeEvent[struct{}]) {
+	for e := range ch {
+		if e.Err != nil {
+			break
+		}
+		key, err := s.getKeyBySuffix(e.Suffix)
+		if err == nil {
+			c.invalidate(key)
+		}
+	}
+	atomic.StoreInt32(&w.running, 0)
+	c.invalidatePrefix(s.getKeyPrefix() + s.KeyDelimiter)
+}
+
+// invalidateNearCache removes cached values of keys written by the store, without waiting for changes to be delivered.
+func (s *redisStore) invalidateNearCache(keys ...string) {
+	if s.NearCache != nil {
+		s.NearCache.invalidate(keys...)
+	}
+}
@@ -0,0 +1,332 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for a condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNearCache(t *testing.T) {
+	defer teardown(t)
+
+	cache := ro.NewNearCache(10, 0)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache))
+
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", UpdatedAt: 100},
+		{ID: 2, Title: "post 2", UpdatedAt: 200},
+	}
+	err := store.Put(context.TODO(), posts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := store.Get(context.TODO(), "1")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := posts[:1]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Get() returned %v, want %v", got, want)
+		}
+	}
+	got, err := store.List(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := posts; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	if got, want := cache.Stats(), (ro.NearCacheStats{Hits: 3, Misses: 2, Len: 2}); got != want {
+		t.Errorf("Stats() returned %+v, want %+v", got, want)
+	}
+
+	// writes of the store itself are reflected immediately
+	posts[0].Title = "updated"
+	err = store.Update(context.TODO(), posts[0], "Title")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, err = store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := posts[:1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+
+	err = store.Delete(context.TODO(), posts[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = store.Get(context.TODO(), "2")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Get() returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestNearCache_InvalidatedByOtherStores(t *testing.T) {
+	defer teardown(t)
+
+	cache := ro.NewNearCache(10, 0)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache))
+	other := ro.NewTyped[*rotesting.Post](pool, ro.WithChangeFeed(true))
+
+	err := other.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = other.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "updated"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, err := store.Get(context.TODO(), "1")
+		return err == nil && got[0].Title == "updated"
+	})
+
+	err = other.DeleteAll(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, err := store.Get(context.TODO(), "1")
+		return errors.Is(err, ro.ErrNotFound)
+	})
+
+	if got := cache.Stats().Invalidations; got == 0 {
+		t.Error("Stats().Invalidations should be counted")
+	}
+}
+
+func TestNearCache_WithTTL(t *testing.T) {
+	defer teardown(t)
+
+	cache := ro.NewNearCache(10, 50*time.Millisecond)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache))
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// changes bypassing stores are not recorded
+	conn := pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("HSET", "Post:1", "title", "updated")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := got[0].Title, "post 1"; got != want {
+		t.Errorf("Get() returned %q, want a cached title %q", got, want)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	got, err = store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := got[0].Title, "updated"; got != want {
+		t.Errorf("Get() returned %q after the TTL, want %q", got, want)
+	}
+}
+
+func TestNearCache_WithModelTTL(t *testing.T) {
+	defer teardown(t)
+
+	cache := ro.NewNearCache(10, 0)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithTTL(50*time.Millisecond), ro.WithNearCache(cache))
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = store.Get(context.TODO(), "1")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	// expired models are read from redis again, since expiry is not recorded in the change feed
+	_, err = store.Get(context.TODO(), "1")
+	if err != nil && !errors.Is(err, ro.ErrNotFound) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stats := cache.Stats()
+	if got, want := stats.Hits, uint64(1); got != want {
+		t.Errorf("Stats().Hits is %d, want %d", got, want)
+	}
+	if got, want := stats.Misses, uint64(2); got != want {
+		t.Errorf("Stats().Misses is %d, want %d", got, want)
+	}
+}
+
+func TestNearCache_Eviction(t *testing.T) {
+	defer teardown(t)
+
+	cache := ro.NewNearCache(2, 0)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache))
+
+	for i := 1; i <= 3; i++ {
+		err := store.Put(context.TODO(), &rotesting.Post{ID: uint64(i)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for _, suffix := range []string{"1", "2", "1", "3", "1", "2"} {
+		_, err := store.Get(context.TODO(), suffix)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if got, want := cache.Stats(), (ro.NearCacheStats{Hits: 2, Misses: 4, Evictions: 2, Len: 2}); got != want {
+		t.Errorf("Stats() returned %+v, want %+v", got, want)
+	}
+}
+
+func TestNearCache_Close(t *testing.T) {
+	defer teardown(t)
+
+	cache := ro.NewNearCache(10, 0)
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache))
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cache.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err = store.Get(context.TODO(), "1")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if got, want := cache.Stats(), (ro.NearCacheStats{Misses: 3}); got != want {
+		t.Errorf("Stats() returned %+v, want %+v", got, want)
+	}
+}
+
+func TestNearCache_WithChangeFeedDisabled(t *testing.T) {
+	defer teardown(t)
+
+	var (
+		mu   sync.Mutex
+		xadd *rq.Command
+	)
+	observer := func(ctx context.Context, e *ro.CommandEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, cmd := range e.Commands {
+			if cmd.Name == "XADD" {
+				xadd = cmd
+			}
+		}
+	}
+
+	cache := ro.NewNearCache(10, 0)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache), ro.WithChangeFeed(false), ro.WithObservers(observer))
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if xadd == nil {
+		t.Fatal("Put() should append a change for the near cache")
+	}
+	if got, want := xadd.Args[:4], []interface{}{"Post#changes", "MAXLEN", "~", int64(10000)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("XADD args start with %v, want %v", got, want)
+	}
+}
+
+func TestNearCache_WithObservers(t *testing.T) {
+	defer teardown(t)
+
+	var (
+		mu    sync.Mutex
+		xread int
+	)
+	observer := func(ctx context.Context, e *ro.CommandEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, cmd := range e.Commands {
+			if cmd.Name == "XREAD" {
+				xread++
+			}
+		}
+	}
+
+	cache := ro.NewNearCache(10, 0)
+	defer cache.Close()
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithNearCache(cache), ro.WithObservers(observer))
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = store.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// XREAD of the watcher returns every second while no changes are made
+	time.Sleep(1500 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := xread, 0; got != want {
+		t.Errorf("Observers received %d XREAD commands, want %d", got, want)
+	}
+}
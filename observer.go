@@ -0,0 +1,141 @@
+package ro
+
+import (
+	"context"
+	"time"
+
+	"github.com/izumin5210/ro/rq"
+)
+
+// CommandEvent describes commands sent to redis in a round trip.
+// Commands sent with Send are reported together when all their replies are received.
+type CommandEvent struct {
+	// Store is a key prefix of the store sending commands.
+	Store string
+	// Operation is a name of the store operation sending commands, e.g. "Put" or "List".
+	Operation string
+	// Commands are commands sent in the round trip. It contains multiple commands for pipelines and transactions.
+	Commands []*rq.Command
+	// Start is the time when commands are written.
+	Start time.Time
+	// Duration is the time taken until all replies are received.
+	Duration time.Duration
+	// Err is the first error of the round trip.
+	Err error
+}
+
+// Observer observes commands sent by stores, e.g. for tracing and latency measurement.
+type Observer func(ctx context.Context, e *CommandEvent)
+
+type operationNameKey struct{}
+
+// OperationName returns a name of the store operation running with the context.
+func OperationName(ctx context.Context) string {
+	name, _ := ctx.Value(operationNameKey{}).(string)
+	return name
+}
+
+func withOperationName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, operationNameKey{}, name)
+}
+
+// getConn acquires a connection, which reports commands to observers of the store.
+func (s *redisStore) getConn(ctx context.Context) (Conn, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil || len(s.Observers) == 0 {
+		return conn, err
+	}
+	return &observedConn{Conn: conn, ctx: ctx, store: s.getKeyPrefix(), observers: s.Observers}, nil
+}
+
+// observedConn reports commands to observers.
+// Commands passed to Send are pending until Flush, and in flight until all their replies are received.
+type observedConn struct {
+	Conn
+	ctx       context.Context
+	store     string
+	observers []Observer
+	pending   []*rq.Command
+	inflight  []*rq.Command
+	received  int
+	start     time.Time
+	err       error
+}
+
+// Do implements the Conn interface.
+func (c *observedConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName != "" {
+		c.pending = append(c.pending, &rq.Command{Name: commandName, Args: args})
+	}
+	c.begin()
+	reply, err := c.Conn.Do(commandName, args...)
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	c.end()
+	return reply, err
+}
+
+// Send implements the Conn interface.
+func (c *observedConn) Send(commandName string, args ...interface{}) error {
+	c.pending = append(c.pending, &rq.Command{Name: commandName, Args: args})
+	return c.Conn.Send(commandName, args...)
+}
+
+// Flush implements the Conn interface.
+func (c *observedConn) Flush() error {
+	c.begin()
+	err := c.Conn.Flush()
+	if err != nil {
+		c.err = err
+		c.end()
+	}
+	return err
+}
+
+// Receive implements the Conn interface.
+func (c *observedConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	if len(c.inflight) == 0 {
+		return reply, err
+	}
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	c.received++
+	if c.received >= len(c.inflight) {
+		c.end()
+	}
+	return reply, err
+}
+
+// begin moves pending commands in flight.
+func (c *observedConn) begin() {
+	if len(c.pending) == 0 {
+		return
+	}
+	if len(c.inflight) == 0 {
+		c.start = time.Now()
+	}
+	c.inflight = append(c.inflight, c.pending...)
+	c.pending = nil
+}
+
+// end reports commands in flight to observers.
+func (c *observedConn) end() {
+	if len(c.inflight) == 0 {
+		return
+	}
+	e := &CommandEvent{
+		Store:     c.store,
+		Operation: OperationName(c.ctx),
+		Commands:  c.inflight,
+		Start:     c.start,
+		Duration:  time.Since(c.start),
+		Err:       c.err,
+	}
+	c.inflight, c.received, c.err = nil, 0, nil
+	for _, o := range c.observers {
+		o(c.ctx, e)
+	}
+}
@@ -0,0 +1,62 @@
+package ro_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestRedisStore_WithObservers(t *testing.T) {
+	defer teardown(t)
+
+	var events []*ro.CommandEvent
+	observer := func(ctx context.Context, e *ro.CommandEvent) {
+		if got, want := ro.OperationName(ctx), e.Operation; got != want {
+			t.Errorf("OperationName() returns %q, want %q", got, want)
+		}
+		events = append(events, e)
+	}
+	store := ro.New(pool, &rotesting.Post{}, ro.WithObservers(observer))
+
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, Title: "post 1", UpdatedAt: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = store.List(context.TODO(), &[]*rotesting.Post{}, rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type roundTrip struct {
+		operation string
+		commands  []string
+	}
+	var got []roundTrip
+	for _, e := range events {
+		if e.Store != "Post" {
+			t.Errorf("Store is %q, want %q", e.Store, "Post")
+		}
+		if e.Err != nil {
+			t.Errorf("Unexpected error: %v", e.Err)
+		}
+		names := make([]string, len(e.Commands))
+		for i, cmd := range e.Commands {
+			names[i] = cmd.Name
+		}
+		got = append(got, roundTrip{operation: e.Operation, commands: names})
+	}
+	want := []roundTrip{
+		{operation: "Put", commands: []string{"WATCH"}},
+		{operation: "Put", commands: []string{"SMEMBERS"}},
+		{operation: "Put", commands: []string{"MULTI", "HMSET", "ZADD", "ZADD", "SADD", "EXEC"}},
+		{operation: "List", commands: []string{"ZRANGE"}},
+		{operation: "List", commands: []string{"HGETALL"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Observed round trips are %v, want %v", got, want)
+	}
+}
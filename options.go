@@ -2,15 +2,29 @@ package ro
 
 import (
 	"reflect"
+	"time"
 )
 
 // Config contains configurations of a store
 type Config struct {
-	KeyPrefix             string
-	ScoreSetKeysKeySuffix string
-	KeyDelimiter          string
-	ScoreKeyDelimiter     string
-	HashStoreEnabled      bool
+	KeyPrefix                string
+	ScoreSetKeysKeySuffix    string
+	KeyDelimiter             string
+	ScoreKeyDelimiter        string
+	HashStoreEnabled         bool
+	HashTagEnabled           bool
+	TTL                      time.Duration
+	ExpirationGracePeriod    time.Duration
+	ExpirationsKeySuffix     string
+	LexSetMembersKeySuffix   string
+	ChangeFeedEnabled        bool
+	ChangeFeedPayloadEnabled bool
+	ChangeFeedKeySuffix      string
+	ChangeFeedMaxLen         int64
+	NearCache                *NearCache
+	Codec                    Codec
+	Interceptors             []Interceptor
+	Observers                []Observer
 }
 
 // Option configures a store
@@ -18,20 +32,36 @@ type Option func(c *Config)
 
 func createConfig(modelType reflect.Type, opts []Option) *Config {
 	cfg := &Config{
-		KeyPrefix:             modelType.Name(),
-		ScoreSetKeysKeySuffix: "scoreSetKeys",
-		KeyDelimiter:          ":",
-		ScoreKeyDelimiter:     "/",
-		HashStoreEnabled:      true,
+		KeyPrefix:              modelType.Name(),
+		ScoreSetKeysKeySuffix:  "scoreSetKeys",
+		KeyDelimiter:           ":",
+		ScoreKeyDelimiter:      "/",
+		HashStoreEnabled:       true,
+		ExpirationGracePeriod:  time.Hour,
+		ExpirationsKeySuffix:   "expirations",
+		LexSetMembersKeySuffix: "lexSetMembers",
+		ChangeFeedKeySuffix:    "changes",
 	}
 
 	for _, f := range opts {
 		f(cfg)
 	}
 
+	if cfg.NearCache != nil {
+		// cached models are invalidated by changes, so the change feed is enabled regardless of the order of options
+		if !cfg.ChangeFeedEnabled && cfg.ChangeFeedMaxLen == 0 {
+			cfg.ChangeFeedMaxLen = nearCacheChangeFeedMaxLen
+		}
+		cfg.ChangeFeedEnabled = true
+	}
+
 	return cfg
 }
 
+// nearCacheChangeFeedMaxLen is the default max length of the change feed enabled only for a near cache,
+// which only needs changes made while it is watching.
+const nearCacheChangeFeedMaxLen = 10000
+
 // WithKeyPrefix returns a StoreOption that specifies key prefix
 // If you does not set this option or set an empty string, it will use a model type name as key prefix.
 func WithKeyPrefix(prefix string) Option {
@@ -61,9 +91,108 @@ func WithScoreKeyDelimiter(d string) Option {
 	}
 }
 
+// WithCodec returns a StoreOption that specifies a codec to store models as strings instead of hashes (default: nil, stores hashes).
+func WithCodec(codec Codec) Option {
+	return func(c *Config) {
+		c.Codec = codec
+	}
+}
+
+// WithInterceptors returns a StoreOption that adds interceptors of store operations.
+// Interceptors are called in order, so the first one is the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Config) {
+		c.Interceptors = append(c.Interceptors, interceptors...)
+	}
+}
+
+// WithObservers returns a StoreOption that adds observers of commands sent by a store.
+func WithObservers(observers ...Observer) Option {
+	return func(c *Config) {
+		c.Observers = append(c.Observers, observers...)
+	}
+}
+
 // WithHashStore returns a StoreOption that enables or disables to store models into redis hash (default: true).
 func WithHashStore(enabled bool) Option {
 	return func(c *Config) {
 		c.HashStoreEnabled = enabled
 	}
 }
+
+// WithHashTag returns a StoreOption that enables or disables to wrap key prefixes in hash tags (default: false).
+// It assigns all keys of a store to the same slot (e.g. `{Post}:1`), which is required by transactions on Redis Cluster.
+func WithHashTag(enabled bool) Option {
+	return func(c *Config) {
+		c.HashTagEnabled = enabled
+	}
+}
+
+// WithTTL returns a StoreOption that specifies a lifetime of stored models (default: 0, never expires).
+// Models implementing Expirer can override it.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.TTL = ttl
+	}
+}
+
+// WithExpirationGracePeriod returns a StoreOption that specifies how long score set bookkeeping keys outlive expired models (default: 1h).
+// Store.Sweep should run within this period to remove memberships of expired models from score sets.
+func WithExpirationGracePeriod(d time.Duration) Option {
+	return func(c *Config) {
+		c.ExpirationGracePeriod = d
+	}
+}
+
+// WithExpirationsKeySuffix returns a StoreOption that specifies a key suffix of the sorted set recording expiration times of models (default: expirations).
+func WithExpirationsKeySuffix(suffix string) Option {
+	return func(c *Config) {
+		c.ExpirationsKeySuffix = suffix
+	}
+}
+
+// WithLexSetMembersKeySuffix returns a StoreOption that specifies a key suffix of hashes recording lex set memberships (default: lexSetMembers).
+func WithLexSetMembersKeySuffix(suffix string) Option {
+	return func(c *Config) {
+		c.LexSetMembersKeySuffix = suffix
+	}
+}
+
+// WithChangeFeed returns a StoreOption that enables or disables to record changes of models into a stream (default: false).
+// Put, Update, Delete and DeleteAll append changes in the same transactions as the changes, and Watch reads them.
+func WithChangeFeed(enabled bool) Option {
+	return func(c *Config) {
+		c.ChangeFeedEnabled = enabled
+	}
+}
+
+// WithChangeFeedPayload returns a StoreOption that enables or disables to record put or updated models in changes (default: false).
+func WithChangeFeedPayload(enabled bool) Option {
+	return func(c *Config) {
+		c.ChangeFeedPayloadEnabled = enabled
+	}
+}
+
+// WithChangeFeedKeySuffix returns a StoreOption that specifies a key suffix of the stream recording changes (default: changes).
+func WithChangeFeedKeySuffix(suffix string) Option {
+	return func(c *Config) {
+		c.ChangeFeedKeySuffix = suffix
+	}
+}
+
+// WithChangeFeedMaxLen returns a StoreOption that specifies an approximate number of changes kept in the stream (default: 0, unlimited).
+func WithChangeFeedMaxLen(n int64) Option {
+	return func(c *Config) {
+		c.ChangeFeedMaxLen = n
+	}
+}
+
+// WithNearCache returns a StoreOption that specifies an in-process cache of models read by Get and List (default: nil, not cached).
+// It enables the change feed even if WithChangeFeed(false) is given, since cached models are invalidated by changes,
+// so stores writing the models should also enable it.
+// The change feed enabled only for the cache keeps about 10000 changes unless WithChangeFeedMaxLen is given.
+func WithNearCache(cache *NearCache) Option {
+	return func(c *Config) {
+		c.NearCache = cache
+	}
+}
@@ -1,7 +1,9 @@
 package ro_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/izumin5210/ro"
 )
@@ -65,3 +67,162 @@ func Test_WithHashStore(t *testing.T) {
 		t.Errorf("StoreConfig.HashStoreEnabled is %t, want %t", got, want)
 	}
 }
+
+func Test_WithHashTag(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := false, cnf.HashTagEnabled; got != want {
+		t.Errorf("StoreConfig.HashTagEnabled is %t, want %t", got, want)
+	}
+	enabled := true
+	ro.WithHashTag(enabled)(cnf)
+	if got, want := enabled, cnf.HashTagEnabled; got != want {
+		t.Errorf("StoreConfig.HashTagEnabled is %t, want %t", got, want)
+	}
+}
+
+func Test_WithTTL(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := time.Duration(0), cnf.TTL; got != want {
+		t.Errorf("StoreConfig.TTL is %v, want %v", got, want)
+	}
+	ttl := 30 * time.Second
+	ro.WithTTL(ttl)(cnf)
+	if got, want := ttl, cnf.TTL; got != want {
+		t.Errorf("StoreConfig.TTL is %v, want %v", got, want)
+	}
+}
+
+func Test_WithExpirationGracePeriod(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := time.Duration(0), cnf.ExpirationGracePeriod; got != want {
+		t.Errorf("StoreConfig.ExpirationGracePeriod is %v, want %v", got, want)
+	}
+	d := 10 * time.Minute
+	ro.WithExpirationGracePeriod(d)(cnf)
+	if got, want := d, cnf.ExpirationGracePeriod; got != want {
+		t.Errorf("StoreConfig.ExpirationGracePeriod is %v, want %v", got, want)
+	}
+}
+
+func Test_WithExpirationsKeySuffix(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := "", cnf.ExpirationsKeySuffix; got != want {
+		t.Errorf("StoreConfig.ExpirationsKeySuffix is %q, want %q", got, want)
+	}
+	suffix := "newsuffix"
+	ro.WithExpirationsKeySuffix(suffix)(cnf)
+	if got, want := suffix, cnf.ExpirationsKeySuffix; got != want {
+		t.Errorf("StoreConfig.ExpirationsKeySuffix is %q, want %q", got, want)
+	}
+}
+
+func Test_WithLexSetMembersKeySuffix(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := "", cnf.LexSetMembersKeySuffix; got != want {
+		t.Errorf("StoreConfig.LexSetMembersKeySuffix is %q, want %q", got, want)
+	}
+	suffix := "newsuffix"
+	ro.WithLexSetMembersKeySuffix(suffix)(cnf)
+	if got, want := suffix, cnf.LexSetMembersKeySuffix; got != want {
+		t.Errorf("StoreConfig.LexSetMembersKeySuffix is %q, want %q", got, want)
+	}
+}
+
+func Test_WithCodec(t *testing.T) {
+	cnf := &ro.Config{}
+	if cnf.Codec != nil {
+		t.Errorf("StoreConfig.Codec is %v, want nil", cnf.Codec)
+	}
+	ro.WithCodec(ro.JSONCodec)(cnf)
+	if got, want := cnf.Codec, ro.JSONCodec; got != want {
+		t.Errorf("StoreConfig.Codec is %v, want %v", got, want)
+	}
+}
+
+func Test_WithInterceptors(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := len(cnf.Interceptors), 0; got != want {
+		t.Errorf("len(StoreConfig.Interceptors) is %d, want %d", got, want)
+	}
+	interceptor := func(ctx context.Context, op *ro.Operation, next ro.Invoker) (interface{}, error) {
+		return next(ctx, op)
+	}
+	ro.WithInterceptors(interceptor, interceptor)(cnf)
+	ro.WithInterceptors(interceptor)(cnf)
+	if got, want := len(cnf.Interceptors), 3; got != want {
+		t.Errorf("len(StoreConfig.Interceptors) is %d, want %d", got, want)
+	}
+}
+
+func Test_WithObservers(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := len(cnf.Observers), 0; got != want {
+		t.Errorf("len(StoreConfig.Observers) is %d, want %d", got, want)
+	}
+	observer := func(ctx context.Context, e *ro.CommandEvent) {}
+	ro.WithObservers(observer, observer)(cnf)
+	if got, want := len(cnf.Observers), 2; got != want {
+		t.Errorf("len(StoreConfig.Observers) is %d, want %d", got, want)
+	}
+}
+
+func Test_WithChangeFeed(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := false, cnf.ChangeFeedEnabled; got != want {
+		t.Errorf("StoreConfig.ChangeFeedEnabled is %t, want %t", got, want)
+	}
+	enabled := true
+	ro.WithChangeFeed(enabled)(cnf)
+	if got, want := enabled, cnf.ChangeFeedEnabled; got != want {
+		t.Errorf("StoreConfig.ChangeFeedEnabled is %t, want %t", got, want)
+	}
+}
+
+func Test_WithChangeFeedPayload(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := false, cnf.ChangeFeedPayloadEnabled; got != want {
+		t.Errorf("StoreConfig.ChangeFeedPayloadEnabled is %t, want %t", got, want)
+	}
+	enabled := true
+	ro.WithChangeFeedPayload(enabled)(cnf)
+	if got, want := enabled, cnf.ChangeFeedPayloadEnabled; got != want {
+		t.Errorf("StoreConfig.ChangeFeedPayloadEnabled is %t, want %t", got, want)
+	}
+}
+
+func Test_WithChangeFeedKeySuffix(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := "", cnf.ChangeFeedKeySuffix; got != want {
+		t.Errorf("StoreConfig.ChangeFeedKeySuffix is %q, want %q", got, want)
+	}
+	suffix := "newsuffix"
+	ro.WithChangeFeedKeySuffix(suffix)(cnf)
+	if got, want := suffix, cnf.ChangeFeedKeySuffix; got != want {
+		t.Errorf("StoreConfig.ChangeFeedKeySuffix is %q, want %q", got, want)
+	}
+}
+
+func Test_WithChangeFeedMaxLen(t *testing.T) {
+	cnf := &ro.Config{}
+	if got, want := int64(0), cnf.ChangeFeedMaxLen; got != want {
+		t.Errorf("StoreConfig.ChangeFeedMaxLen is %d, want %d", got, want)
+	}
+	n := int64(1000)
+	ro.WithChangeFeedMaxLen(n)(cnf)
+	if got, want := n, cnf.ChangeFeedMaxLen; got != want {
+		t.Errorf("StoreConfig.ChangeFeedMaxLen is %d, want %d", got, want)
+	}
+}
+
+func Test_WithNearCache(t *testing.T) {
+	cnf := &ro.Config{}
+	if cnf.NearCache != nil {
+		t.Errorf("StoreConfig.NearCache is %v, want nil", cnf.NearCache)
+	}
+	cache := ro.NewNearCache(10, 0)
+	defer cache.Close()
+	ro.WithNearCache(cache)(cnf)
+	if got, want := cnf.NearCache, cache; got != want {
+		t.Errorf("StoreConfig.NearCache is %v, want %v", got, want)
+	}
+}
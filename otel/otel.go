@@ -0,0 +1,67 @@
+// Package rootel provides OpenTelemetry tracing of stores.
+package rootel
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/izumin5210/ro"
+)
+
+// Attribute keys of spans.
+const (
+	StoreKey     = attribute.Key("ro.store")
+	OperationKey = attribute.Key("ro.operation")
+)
+
+// NewObserver returns ro.Observer which records each round trip of commands as a client span.
+// Spans contain only command names, since command args can contain values of models.
+func NewObserver(tracer trace.Tracer) ro.Observer {
+	return func(ctx context.Context, e *ro.CommandEvent) {
+		names := make([]string, len(e.Commands))
+		for i, cmd := range e.Commands {
+			names[i] = cmd.Name
+		}
+		spanName := names[0]
+		if len(names) > 1 {
+			spanName = "pipeline"
+		}
+
+		_, span := tracer.Start(ctx, spanName,
+			trace.WithTimestamp(e.Start),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", strings.Join(names, " ")),
+				attribute.Int("db.redis.commands", len(names)),
+				StoreKey.String(e.Store),
+				OperationKey.String(e.Operation),
+			),
+		)
+		if e.Err != nil {
+			span.RecordError(e.Err)
+			span.SetStatus(codes.Error, e.Err.Error())
+		}
+		span.End(trace.WithTimestamp(e.Start.Add(e.Duration)))
+	}
+}
+
+// NewInterceptor returns ro.Interceptor which records each store operation as a span,
+// so spans of commands recorded by NewObserver are grouped by operations.
+func NewInterceptor(tracer trace.Tracer) ro.Interceptor {
+	return func(ctx context.Context, op *ro.Operation, next ro.Invoker) (interface{}, error) {
+		ctx, span := tracer.Start(ctx, "ro."+op.Name, trace.WithAttributes(OperationKey.String(op.Name)))
+		defer span.End()
+
+		result, err := next(ctx, op)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,58 @@
+package rootel_test
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/memstore"
+	rootel "github.com/izumin5210/ro/otel"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestNewObserver(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("ro")
+
+	store := ro.New(memstore.NewPool(), &rotesting.Post{},
+		ro.WithInterceptors(rootel.NewInterceptor(tracer)),
+		ro.WithObservers(rootel.NewObserver(tracer)),
+	)
+	err := store.GetFields(context.Background(), []string{"title"}, &rotesting.Post{ID: 1})
+	if err == nil {
+		t.Fatal("Get should return an error")
+	}
+
+	spans := recorder.Ended()
+	if got, want := len(spans), 3; got != want {
+		t.Fatalf("%d spans are recorded, want %d", got, want)
+	}
+
+	for i, name := range []string{"HMGET", "EXISTS", "ro.Get"} {
+		span := spans[i]
+		if got, want := span.Name(), name; got != want {
+			t.Errorf("Span name is %q, want %q", got, want)
+		}
+		if i < 2 && span.Parent().SpanID() != spans[2].SpanContext().SpanID() {
+			t.Errorf("Span %q should be a child of %q", span.Name(), spans[2].Name())
+		}
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	for k, want := range map[string]string{
+		"db.system":    "redis",
+		"db.operation": "HMGET",
+		"ro.store":     "Post",
+		"ro.operation": "Get",
+	} {
+		if got := attrs[k]; got != want {
+			t.Errorf("Attribute %s is %q, want %q", k, got, want)
+		}
+	}
+}
@@ -5,34 +5,233 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
 )
 
+// txRetryLimit is the number of times a store retries a transaction aborted by a concurrent update of watched keys.
+const txRetryLimit = 10
+
+var errTxAborted = errors.New("transaction was aborted")
+
 // Put implements the types.Store interface.
 func (s *redisStore) Put(ctx context.Context, src interface{}) error {
-	conn, err := s.pool.GetContext(ctx)
+	_, err := s.intercept(ctx, &Operation{Name: "Put", Models: src}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return nil, s.doPut(ctx, op.Models, 0)
+	})
+	return err
+}
+
+// putWithTTL puts models with the TTL instead of TTLs of the store or models.
+func (s *redisStore) putWithTTL(ctx context.Context, src interface{}, ttl time.Duration) error {
+	_, err := s.intercept(ctx, &Operation{Name: "Put", Models: src}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return nil, s.doPut(ctx, op.Models, ttl)
+	})
+	return err
+}
+
+// doPut puts models, which expire after ttl when it is positive.
+func (s *redisStore) doPut(ctx context.Context, src interface{}, ttl time.Duration) error {
+	models, err := s.toModels(reflect.ValueOf(src))
+	if err != nil {
+		return errors.Wrap(err, "faild to send any commands")
+	}
+	if len(models) == 0 {
+		return nil
+	}
+
+	schema, err := getSchema(s.modelType)
+	if err != nil {
+		return errors.Wrap(err, "invalid model schema")
+	}
+	var version *versionField
+	if s.HashStoreEnabled {
+		version = schema.version
+	}
+
+	now := time.Now()
+	entries := make([]*putEntry, len(models))
+	for i, m := range models {
+		entries[i], err = s.createPutEntry(m, now)
+		if err != nil {
+			return errors.Wrap(err, "faild to send any commands")
+		}
+		if version != nil {
+			entries[i].version = version.get(m)
+		}
+		if ttl > 0 {
+			entries[i].expireAt = now.Add(ttl)
+		}
+	}
+
+	conn, err := s.getConn(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to acquire a connection")
 	}
 	defer conn.Close()
 
+	for i := 0; i < txRetryLimit; i++ {
+		err = s.put(conn, entries, version)
+		if errors.Cause(err) != errTxAborted {
+			break
+		}
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, e := range entries {
+		s.invalidateNearCache(e.key)
+	}
+
+	if version != nil {
+		for _, e := range entries {
+			version.set(e.model, e.version+1)
+		}
+	}
+
+	return nil
+}
+
+type putEntry struct {
+	model      interface{}
+	key        string
+	scores     map[string]interface{}
+	lexMembers map[string]string
+	expireAt   time.Time
+	version    int64
+}
+
+func (s *redisStore) createPutEntry(m interface{}, now time.Time) (*putEntry, error) {
+	key, err := s.getKey(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get key")
+	}
+
+	scoreMap, err := getScoreMap(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get scores")
+	}
+	if scoreMap == nil {
+		return nil, errors.Errorf("%s's GetScoreMap() should be present", key)
+	}
+
+	scores := make(map[string]interface{}, len(scoreMap))
+	for ks, score := range scoreMap {
+		if len(ks) == 0 {
+			return nil, errors.Errorf("key in %s's GetScoreMap() should be present", key)
+		}
+		_, err := strconv.ParseFloat(fmt.Sprint(score), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s's GetScoreMap()[%s] should be number", key, ks)
+		}
+		scores[s.getScoreSetKey(ks)] = score
+	}
+
+	e := &putEntry{model: m, key: key, scores: scores}
+
+	if indexer, ok := m.(LexIndexer); ok {
+		lexMap := indexer.GetLexMap()
+		e.lexMembers = make(map[string]string, len(lexMap))
+		for ks, value := range lexMap {
+			if len(ks) == 0 {
+				return nil, errors.Errorf("key in %s's GetLexMap() should be present", key)
+			}
+			lexSetKey := s.getScoreSetKey(ks)
+			if _, ok := scores[lexSetKey]; ok {
+				return nil, errors.Errorf("%s's GetLexMap()[%s] conflicts with GetScoreMap()", key, ks)
+			}
+			e.lexMembers[lexSetKey] = getLexMember(value, key)
+		}
+	}
+	if ttl := s.getTTL(m); ttl > 0 {
+		e.expireAt = now.Add(ttl)
+	}
+
+	return e, nil
+}
+
+// put writes entries in a transaction.
+// Keys recording score set memberships are watched so that memberships no longer produced by models can be removed atomically.
+// When the version field is given, hashes are also watched and their stored versions are compared with versions of entries.
+func (s *redisStore) put(conn Conn, entries []*putEntry, version *versionField) error {
+	scoreSetKeysKeys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		scoreSetKeysKeys = append(scoreSetKeysKeys, s.getScoreSetKeysKeyByKey(e.key))
+	}
+
+	watchedKeys := redis.Args{}.AddFlat(scoreSetKeysKeys)
+	var lexSetMembersKeys []string
+	if s.isLexIndexed() {
+		lexSetMembersKeys = make([]string, 0, len(entries))
+		for _, e := range entries {
+			lexSetMembersKeys = append(lexSetMembersKeys, s.getLexSetMembersKeyByKey(e.key))
+		}
+		watchedKeys = watchedKeys.AddFlat(lexSetMembersKeys)
+	}
+	if version != nil {
+		for _, e := range entries {
+			watchedKeys = watchedKeys.Add(e.key)
+		}
+	}
+
+	_, err := conn.Do("WATCH", watchedKeys...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to execute WATCH %v", watchedKeys)
+	}
+
+	zsetKeysByKey, err := s.getScoreSetKeysByKeys(conn, scoreSetKeysKeys)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return errors.WithStack(err)
+	}
+
+	lexMembersByKey, err := s.getLexMembersByKeys(conn, lexSetMembersKeys)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return errors.WithStack(err)
+	}
+
+	if version != nil {
+		err = s.checkVersions(conn, entries, version)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return errors.WithStack(err)
+		}
+	}
+
 	err = conn.Send("MULTI")
 	if err != nil {
+		conn.Do("UNWATCH")
 		return errors.Wrap(err, "faild to send MULTI command")
 	}
 
-	rv := reflect.ValueOf(src)
-	if rv.Kind() == reflect.Slice {
-		for i := 0; i < rv.Len(); i++ {
-			err = s.set(conn, rv.Index(i))
+	for _, e := range entries {
+		scoreSetKeysKey := s.getScoreSetKeysKeyByKey(e.key)
+		lexSetMembersKey := s.getLexSetMembersKeyByKey(e.key)
+		err = s.set(conn, e, zsetKeysByKey[scoreSetKeysKey], lexMembersByKey[lexSetMembersKey])
+		if err != nil {
+			break
+		}
+		if version != nil && s.Codec == nil {
+			err = conn.Send("HSET", e.key, version.hashField, e.version+1)
 			if err != nil {
+				err = errors.Wrapf(err, "failed to send HSET %s %s %d", e.key, version.hashField, e.version+1)
 				break
 			}
 		}
-	} else {
-		err = s.set(conn, rv)
+		if s.ChangeFeedEnabled {
+			err = s.sendEntryChange(conn, ChangePut, e, nil, zsetKeysByKey[scoreSetKeysKey], version)
+			if err != nil {
+				break
+			}
+		}
+		zsetKeysByKey[scoreSetKeysKey] = e.getScoreSetKeys()
+		if lexMembersByKey != nil {
+			lexMembersByKey[lexSetMembersKey] = e.lexMembers
+		}
 	}
 
 	if err != nil {
@@ -40,59 +239,349 @@ func (s *redisStore) Put(ctx context.Context, src interface{}) error {
 		return errors.Wrap(err, "faild to send any commands")
 	}
 
-	_, err = conn.Do("EXEC")
+	reply, err := conn.Do("EXEC")
 	if err != nil {
 		return errors.Wrap(err, "faild to EXEC commands")
 	}
+	if reply == nil {
+		return errTxAborted
+	}
 	return nil
 }
 
-func (s *redisStore) set(conn redis.Conn, src reflect.Value) error {
-	m, err := s.toModel(src)
+// checkVersions returns a ConflictError when a stored version differs from a version of an entry.
+func (s *redisStore) checkVersions(conn Conn, entries []*putEntry, version *versionField) error {
+	for _, e := range entries {
+		var err error
+		if s.Codec != nil {
+			err = conn.Send("GET", e.key)
+		} else {
+			err = conn.Send("HGET", e.key, version.hashField)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to send a command reading a version of %s", e.key)
+		}
+	}
+
+	err := conn.Flush()
 	if err != nil {
-		return errors.Wrap(err, "failed to convert to model")
+		return errors.Wrap(err, "faild to flush commands reading versions")
 	}
 
-	key, err := s.getKey(m)
+	var conflict error
+	storedVersions := make(map[string]int64, len(entries))
+	for _, e := range entries {
+		stored, err := s.receiveVersion(conn, version)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read a version of %s", e.key)
+		}
+		if v, ok := storedVersions[e.key]; ok {
+			stored = v
+		}
+		if conflict == nil && stored != e.version {
+			conflict = &ConflictError{Key: e.key, Version: e.version, StoredVersion: stored}
+		}
+		storedVersions[e.key] = e.version + 1
+	}
 
+	return conflict
+}
+
+// receiveVersion receives a stored version, or 0 when a model is not stored.
+func (s *redisStore) receiveVersion(conn Conn, version *versionField) (int64, error) {
+	if s.Codec == nil {
+		stored, err := redis.Int64(conn.Receive())
+		if err == redis.ErrNil {
+			return 0, nil
+		}
+		return stored, err
+	}
+
+	data, err := redis.Bytes(conn.Receive())
+	if err == redis.ErrNil {
+		return 0, nil
+	}
 	if err != nil {
-		return errors.Wrap(err, "failed to get key")
+		return 0, err
 	}
+	v, err := s.decode(data)
+	if err != nil {
+		return 0, err
+	}
+	return version.get(v.Addr().Interface()), nil
+}
 
-	if s.HashStoreEnabled {
-		err = conn.Send("HMSET", redis.Args{}.Add(key).AddFlat(m)...)
+func (s *redisStore) getScoreSetKeysByKeys(conn Conn, scoreSetKeysKeys []string) (map[string][]string, error) {
+	for _, k := range scoreSetKeysKeys {
+		err := conn.Send("SMEMBERS", k)
 		if err != nil {
-			return errors.Wrapf(err, "failed to send HMEST %s %v", key, m)
+			return nil, errors.Wrapf(err, "failed to send SMEMBERS %s", k)
 		}
 	}
 
-	scoreMap := m.GetScoreMap()
-	if scoreMap == nil {
-		return errors.Errorf("%s's GetScoreMap() should be present", key)
+	err := conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush SMEMBERS commands")
 	}
 
-	zsetKeys := make([]string, 0, len(scoreMap))
-	for ks, score := range scoreMap {
-		if len(ks) == 0 {
-			return errors.Errorf("key in %s's GetScoreMap() should be present", key)
+	zsetKeysByKey := make(map[string][]string, len(scoreSetKeysKeys))
+	for _, k := range scoreSetKeysKeys {
+		zsetKeys, err := redis.Strings(conn.Receive())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to execute SMEMBERS %s", k)
 		}
-		_, err := strconv.ParseFloat(fmt.Sprint(score), 64)
+		zsetKeysByKey[k] = zsetKeys
+	}
+
+	return zsetKeysByKey, nil
+}
+
+// getLexMembersByKeys returns lex set members recorded in hashes, which are keyed by lex set keys.
+func (s *redisStore) getLexMembersByKeys(conn Conn, lexSetMembersKeys []string) (map[string]map[string]string, error) {
+	if len(lexSetMembersKeys) == 0 {
+		return nil, nil
+	}
+
+	for _, k := range lexSetMembersKeys {
+		err := conn.Send("HGETALL", k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to send HGETALL %s", k)
+		}
+	}
+
+	err := conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush HGETALL commands")
+	}
+
+	lexMembersByKey := make(map[string]map[string]string, len(lexSetMembersKeys))
+	for _, k := range lexSetMembersKeys {
+		members, err := redis.StringMap(conn.Receive())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to execute HGETALL %s", k)
+		}
+		lexMembersByKey[k] = members
+	}
+
+	return lexMembersByKey, nil
+}
+
+func (s *redisStore) set(conn Conn, e *putEntry, currentZsetKeys []string, currentLexMembers map[string]string) error {
+	key, m := e.key, e.model
+
+	if s.HashStoreEnabled && s.Codec != nil {
+		data, err := s.encode(m, e.version+1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = conn.Send("SET", key, data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send SET %s %q", key, data)
+		}
+	} else if s.HashStoreEnabled {
+		pairs, nulls, err := toHash(m)
 		if err != nil {
-			return errors.Wrapf(err, "%s's GetScoreMap()[%s] should be number", key, ks)
+			return errors.WithStack(err)
 		}
-		scoreSetKey := s.getScoreSetKey(ks)
-		err = conn.Send("ZADD", scoreSetKey, score, key)
+		err = sendHash(conn, key, pairs, nulls)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	err := s.setScores(conn, e, currentZsetKeys)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = s.setLexMembers(conn, e, currentLexMembers)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = s.setExpiration(conn, e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// sendHash sets field-value pairs into the hash, and removes fields of nil values.
+func sendHash(conn Conn, key string, pairs []interface{}, nulls []string) error {
+	if len(pairs) > 0 {
+		err := conn.Send("HMSET", redis.Args{}.Add(key).Add(pairs...)...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send HMEST %s %v", key, pairs)
+		}
+	}
+	if len(nulls) > 0 {
+		err := conn.Send("HDEL", redis.Args{}.Add(key).AddFlat(nulls)...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send HDEL %s %v", key, nulls)
+		}
+	}
+	return nil
+}
+
+// setScores adds the key to score sets, and removes it from score sets no longer produced by the model.
+func (s *redisStore) setScores(conn Conn, e *putEntry, currentZsetKeys []string) error {
+	key := e.key
+
+	for scoreSetKey, score := range e.scores {
+		err := conn.Send("ZADD", scoreSetKey, score, key)
 		if err != nil {
 			return errors.Wrapf(err, "failed to send ZADD %s %v %s", scoreSetKey, score, key)
 		}
-		zsetKeys = append(zsetKeys, scoreSetKey)
 	}
 
 	scoreSetKeysKey := s.getScoreSetKeysKeyByKey(key)
-	err = conn.Send("SADD", redis.Args{}.Add(scoreSetKeysKey).AddFlat(zsetKeys)...)
+
+	staleZsetKeys := make([]string, 0, len(currentZsetKeys))
+	for _, zk := range currentZsetKeys {
+		if _, ok := e.scores[zk]; !ok {
+			staleZsetKeys = append(staleZsetKeys, zk)
+		}
+	}
+
+	if len(staleZsetKeys) > 0 {
+		for _, zk := range staleZsetKeys {
+			err := conn.Send("ZREM", zk, key)
+			if err != nil {
+				return errors.Wrapf(err, "failed to send ZREM %s %s", zk, key)
+			}
+		}
+		err := conn.Send("SREM", redis.Args{}.Add(scoreSetKeysKey).AddFlat(staleZsetKeys)...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send SREM %s %v", scoreSetKeysKey, staleZsetKeys)
+		}
+	}
+
+	zsetKeys := e.getScoreSetKeys()
+	if len(zsetKeys) > 0 {
+		err := conn.Send("SADD", redis.Args{}.Add(scoreSetKeysKey).AddFlat(zsetKeys)...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send SADD %s %v", scoreSetKeysKey, zsetKeys)
+		}
+	}
+
+	return nil
+}
+
+// setLexMembers adds members of lex sets and removes members whose values were changed or are no longer produced by the model.
+func (s *redisStore) setLexMembers(conn Conn, e *putEntry, currentLexMembers map[string]string) error {
+	if !s.isLexIndexed() {
+		return nil
+	}
+
+	lexSetMembersKey := s.getLexSetMembersKeyByKey(e.key)
+
+	staleFields := make([]string, 0, len(currentLexMembers))
+	for zk, member := range currentLexMembers {
+		newMember, ok := e.lexMembers[zk]
+		if ok && newMember == member {
+			continue
+		}
+		err := conn.Send("ZREM", zk, member)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send ZREM %s %q", zk, member)
+		}
+		if !ok {
+			staleFields = append(staleFields, zk)
+		}
+	}
+
+	if len(staleFields) > 0 {
+		err := conn.Send("HDEL", redis.Args{}.Add(lexSetMembersKey).AddFlat(staleFields)...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send HDEL %s %v", lexSetMembersKey, staleFields)
+		}
+	}
+
+	if len(e.lexMembers) == 0 {
+		return nil
+	}
+
+	for zk, member := range e.lexMembers {
+		err := conn.Send("ZADD", zk, 0, member)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send ZADD %s 0 %q", zk, member)
+		}
+	}
+
+	err := conn.Send("HMSET", redis.Args{}.Add(lexSetMembersKey).AddFlat(e.lexMembers)...)
 	if err != nil {
-		return errors.Wrapf(err, "failed to send SADD %s %v", scoreSetKeysKey, zsetKeys)
+		return errors.Wrapf(err, "failed to send HMSET %s %v", lexSetMembersKey, e.lexMembers)
 	}
 
 	return nil
 }
+
+// setExpiration applies a TTL of the entry to the hash and the bookkeeping set, and records the expiration time so that Sweep can find expired models.
+// The bookkeeping set outlives the hash for ExpirationGracePeriod since Sweep reads it to remove score set memberships.
+func (s *redisStore) setExpiration(conn Conn, e *putEntry) error {
+	key := e.key
+	scoreSetKeysKey := s.getScoreSetKeysKeyByKey(key)
+	expirationsKey := s.getExpirationsKey()
+
+	if e.expireAt.IsZero() {
+		if !s.isExpirable() {
+			return nil
+		}
+		if s.HashStoreEnabled {
+			err := conn.Send("PERSIST", key)
+			if err != nil {
+				return errors.Wrapf(err, "failed to send PERSIST %s", key)
+			}
+		}
+		err := conn.Send("PERSIST", scoreSetKeysKey)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send PERSIST %s", scoreSetKeysKey)
+		}
+		if s.isLexIndexed() {
+			lexSetMembersKey := s.getLexSetMembersKeyByKey(key)
+			err = conn.Send("PERSIST", lexSetMembersKey)
+			if err != nil {
+				return errors.Wrapf(err, "failed to send PERSIST %s", lexSetMembersKey)
+			}
+		}
+		err = conn.Send("ZREM", expirationsKey, key)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send ZREM %s %s", expirationsKey, key)
+		}
+		return nil
+	}
+
+	at := toMilliseconds(e.expireAt)
+	if s.HashStoreEnabled {
+		err := conn.Send("PEXPIREAT", key, at)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send PEXPIREAT %s %d", key, at)
+		}
+	}
+	graceAt := toMilliseconds(e.expireAt.Add(s.ExpirationGracePeriod))
+	err := conn.Send("PEXPIREAT", scoreSetKeysKey, graceAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send PEXPIREAT %s %d", scoreSetKeysKey, graceAt)
+	}
+	if s.isLexIndexed() {
+		lexSetMembersKey := s.getLexSetMembersKeyByKey(key)
+		err = conn.Send("PEXPIREAT", lexSetMembersKey, graceAt)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send PEXPIREAT %s %d", lexSetMembersKey, graceAt)
+		}
+	}
+	err = conn.Send("ZADD", expirationsKey, at, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send ZADD %s %d %s", expirationsKey, at, key)
+	}
+	return nil
+}
+
+func (e *putEntry) getScoreSetKeys() []string {
+	keys := make([]string, 0, len(e.scores))
+	for k := range e.scores {
+		keys = append(keys, k)
+	}
+	return keys
+}
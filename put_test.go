@@ -2,8 +2,10 @@ package ro_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -156,6 +158,58 @@ func TestRedisStore_Put_WithMultipleItems(t *testing.T) {
 	}
 }
 
+func TestRedisStore_Put_WhenScoreMapChanged(t *testing.T) {
+	defer teardown(t)
+	now := time.Now().UTC()
+	post := &Post{
+		ID:        1,
+		UserID:    1,
+		Title:     "post 1",
+		Body:      "This is a post 1.",
+		CreatedAt: now.UnixNano(),
+	}
+
+	store := ro.New(pool, &Post{})
+	err := store.Put(context.TODO(), post)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	post.UserID = 2
+	err = store.Put(context.TODO(), post)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("ZRANGE", "Post/user:1", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := keys, []string{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Post/user:1 contains %v, want %v", got, want)
+	}
+
+	keys, err = redis.Strings(conn.Do("ZRANGE", "Post/user:2", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := keys, []string{"Post:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Post/user:2 contains %v, want %v", got, want)
+	}
+
+	keys, err = redis.Strings(conn.Do("SMEMBERS", "Post:1:scoreSetKeys"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"Post/recent", "Post/user:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Post:1:scoreSetKeys contains %v, want %v", got, want)
+	}
+}
+
 func TestRedisStore_Put_WhenDisableToStoreToHash(t *testing.T) {
 	defer teardown(t)
 	now := time.Now().UTC()
@@ -193,6 +247,78 @@ func TestRedisStore_Put_WhenDisableToStoreToHash(t *testing.T) {
 	}
 }
 
+type VersionedPost struct {
+	ID      uint64 `redis:"id"`
+	Title   string `redis:"title"`
+	Version int64  `redis:"version" ro:"version"`
+}
+
+func (p *VersionedPost) GetKeySuffix() string {
+	return fmt.Sprint(p.ID)
+}
+
+func (p *VersionedPost) GetScoreMap() map[string]interface{} {
+	return map[string]interface{}{"id": p.ID}
+}
+
+func TestRedisStore_Put_WithVersion(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &VersionedPost{})
+	post := &VersionedPost{ID: 1, Title: "post 1"}
+
+	err := store.Put(context.TODO(), post)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := post.Version, int64(1); got != want {
+		t.Errorf("Put() set version %d, want %d", got, want)
+	}
+
+	stale := &VersionedPost{ID: 1, Title: "stale post 1"}
+	err = store.Put(context.TODO(), stale)
+	if err == nil {
+		t.Error("Put() with a stale version should return an error")
+	}
+	if !errors.Is(err, ro.ErrConflict) {
+		t.Errorf("Put() with a stale version returned %v, want ErrConflict", err)
+	}
+	var conflictErr *ro.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Errorf("Put() with a stale version returned %v, want ConflictError", err)
+	} else if got, want := conflictErr.StoredVersion, int64(1); got != want {
+		t.Errorf("ConflictError.StoredVersion is %d, want %d", got, want)
+	}
+	if got, want := stale.Version, int64(0); got != want {
+		t.Errorf("Put() with a stale version set version %d, want %d", got, want)
+	}
+
+	post.Title = "updated post 1"
+	err = store.Put(context.TODO(), post)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := post.Version, int64(2); got != want {
+		t.Errorf("Put() set version %d, want %d", got, want)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	v, err := redis.Values(conn.Do("HGETALL", "VersionedPost:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	gotPost := &VersionedPost{}
+	err = redis.ScanStruct(v, gotPost)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPost, post; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored post is %v, want %v", got, want)
+	}
+}
+
 type DummyWithEmptyKeySuffix struct {
 }
 
@@ -366,3 +492,144 @@ func TestRedisStore_Put_WithStringNumberScore(t *testing.T) {
 		t.Errorf("Put() with string number score stores %d items, want %d items", got, want)
 	}
 }
+
+type TaggedPost struct {
+	ID        uint64 `redis:"id" ro:"key;score=id"`
+	UserID    uint64 `redis:"user_id" ro:"score=user:{UserID},value=CreatedAt"`
+	Title     string `redis:"title"`
+	CreatedAt int64  `redis:"created_at" ro:"score=recent"`
+}
+
+func TestRedisStore_Put_WithTaggedModel(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &TaggedPost{})
+	post := &TaggedPost{ID: 1, UserID: 2, Title: "post 1", CreatedAt: 100}
+
+	err := store.Put(context.TODO(), post)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "TaggedPost*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"TaggedPost/id", "TaggedPost/recent", "TaggedPost/user:2", "TaggedPost:1", "TaggedPost:1:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+
+	for k, want := range map[string]int64{"TaggedPost/id": 1, "TaggedPost/recent": 100, "TaggedPost/user:2": 100} {
+		got, err := redis.Int64(conn.Do("ZSCORE", k, "TaggedPost:1"))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("Score in %s is %d, want %d", k, got, want)
+		}
+	}
+
+	gotPost := &TaggedPost{ID: 1}
+	err = store.Get(context.TODO(), gotPost)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPost, post; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+}
+
+type TaggedPostWithModel struct {
+	ID    uint64 `redis:"id" ro:"key;score=id"`
+	Title string `redis:"title"`
+}
+
+func (p *TaggedPostWithModel) GetKeySuffix() string {
+	return fmt.Sprintf("post-%d", p.ID)
+}
+
+func (p *TaggedPostWithModel) GetScoreMap() map[string]interface{} {
+	return map[string]interface{}{"custom": p.ID}
+}
+
+func TestRedisStore_Put_WithTaggedModelImplementingModel(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &TaggedPostWithModel{})
+	err := store.Put(context.TODO(), &TaggedPostWithModel{ID: 1, Title: "post 1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "TaggedPostWithModel*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"TaggedPostWithModel/custom", "TaggedPostWithModel:post-1", "TaggedPostWithModel:post-1:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Put_WithInvalidTags(t *testing.T) {
+	defer teardown(t)
+
+	cases := []struct {
+		name  string
+		model interface{}
+	}{
+		{
+			name:  "without key",
+			model: &struct{ ID uint64 }{},
+		},
+		{
+			name: "with multiple keys",
+			model: &struct {
+				ID   uint64 `ro:"key"`
+				Name string `ro:"key"`
+			}{},
+		},
+		{
+			name: "with a non-number score",
+			model: &struct {
+				ID   uint64 `ro:"key"`
+				Name string `ro:"score=name"`
+			}{},
+		},
+		{
+			name: "with an unknown field in a score name",
+			model: &struct {
+				ID uint64 `ro:"key;score=user:{UserID}"`
+			}{},
+		},
+		{
+			name: "with an unclosed brace in a score name",
+			model: &struct {
+				ID uint64 `ro:"key;score=user:{ID"`
+			}{},
+		},
+		{
+			name: "with an unknown value field",
+			model: &struct {
+				ID uint64 `ro:"key;score=recent,value=CreatedAt"`
+			}{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := ro.New(pool, c.model, ro.WithKeyPrefix("Invalid"))
+			err := store.Put(context.TODO(), c.model)
+			if err == nil {
+				t.Error("Put() should return an error")
+			}
+		})
+	}
+}
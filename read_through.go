@@ -0,0 +1,317 @@
+package ro
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Loader loads models missing in a store from a source of truth, such as a database.
+// Load returns found models, and models which are not returned are treated as missing.
+type Loader[T any] interface {
+	Load(ctx context.Context, suffixes []string) ([]T, error)
+}
+
+// LoaderFunc is an adapter to use ordinary functions as Loader.
+type LoaderFunc[T any] func(ctx context.Context, suffixes []string) ([]T, error)
+
+// Load implements the Loader interface.
+func (f LoaderFunc[T]) Load(ctx context.Context, suffixes []string) ([]T, error) {
+	return f(ctx, suffixes)
+}
+
+// ReadThroughConfig contains configurations of a read-through store
+type ReadThroughConfig struct {
+	NegativeTTL time.Duration
+	LoadedTTL   time.Duration
+}
+
+// WithLoadedTTL returns a ReadThroughOption that specifies a lifetime of loaded models put back to the store (default: 0, the TTL of the store).
+// The store should be created by NewTyped with WithTTL or Expirer, so that List and Count ignore expired models until Sweep removes them.
+// Otherwise loads fail with ErrLoadedTTLUnsupported.
+func WithLoadedTTL(ttl time.Duration) ReadThroughOption {
+	return func(c *ReadThroughConfig) {
+		c.LoadedTTL = ttl
+	}
+}
+
+// ttlPutter is implemented by stores which can put models with TTLs given by read-through stores.
+type ttlPutter[T any] interface {
+	putWithTTL(ctx context.Context, ttl time.Duration, srcs ...T) error
+	isExpirable() bool
+}
+
+// ReadThroughOption configures a read-through store
+type ReadThroughOption func(c *ReadThroughConfig)
+
+// WithNegativeTTL returns a ReadThroughOption that specifies how long missing models are remembered without loading them again (default: 0, not remembered).
+func WithNegativeTTL(ttl time.Duration) ReadThroughOption {
+	return func(c *ReadThroughConfig) {
+		c.NegativeTTL = ttl
+	}
+}
+
+type readThroughStore[T any] struct {
+	TypedStore[T]
+	*ReadThroughConfig
+	loader Loader[T]
+
+	mu     sync.Mutex
+	calls  map[string]*loadCall[T]
+	misses map[string]time.Time
+}
+
+// loadCall is an in-flight or completed load of a model.
+type loadCall[T any] struct {
+	done  chan struct{}
+	model T
+	found bool
+	err   error
+}
+
+// NewReadThrough creates a TypedStore which loads models missing in the store with the loader on Get, and puts them back to the store.
+// Loaded models are put with the TTL of the store (WithTTL or Expirer) unless WithLoadedTTL is given.
+// Concurrent loads of the same key suffix are collapsed into a single load, which is not canceled by any of callers.
+func NewReadThrough[T any](store TypedStore[T], loader Loader[T], opts ...ReadThroughOption) TypedStore[T] {
+	cfg := &ReadThroughConfig{}
+	for _, f := range opts {
+		f(cfg)
+	}
+
+	return &readThroughStore[T]{
+		TypedStore:        store,
+		ReadThroughConfig: cfg,
+		loader:            loader,
+		calls:             map[string]*loadCall[T]{},
+		misses:            map[string]time.Time{},
+	}
+}
+
+// Get implements the TypedStore interface.
+// Models missing in the store are loaded with the loader, and a NotFoundError is returned when the loader does not find them either.
+func (s *readThroughStore[T]) Get(ctx context.Context, suffixes ...string) ([]T, error) {
+	var cached []string
+	missing := map[string]bool{}
+	for _, suffix := range suffixes {
+		if s.isMissing(suffix) {
+			missing[suffix] = true
+		} else {
+			cached = append(cached, suffix)
+		}
+	}
+
+	var models []T
+	if len(cached) > 0 {
+		var err error
+		models, err = s.TypedStore.Get(ctx, cached...)
+		var nfErr *NotFoundError
+		if errors.As(err, &nfErr) {
+			err = nil
+			for _, suffix := range nfErr.Suffixes {
+				missing[suffix] = false
+			}
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	var loading []string
+	for suffix, known := range missing {
+		if !known {
+			loading = append(loading, suffix)
+		}
+	}
+	loaded, err := s.load(ctx, loading)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	results := make([]T, 0, len(suffixes))
+	found := make([]bool, len(suffixes))
+	for i, suffix := range suffixes {
+		if _, ok := missing[suffix]; !ok {
+			results = append(results, models[0])
+			models = models[1:]
+			found[i] = true
+		} else if m, ok := loaded[suffix]; ok {
+			results = append(results, m)
+			found[i] = true
+		}
+	}
+
+	return results, newNotFoundError(suffixes, found)
+}
+
+// Put implements the TypedStore interface.
+// Put models are no longer treated as missing.
+func (s *readThroughStore[T]) Put(ctx context.Context, srcs ...T) error {
+	err := s.TypedStore.Put(ctx, srcs...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	suffixes, err := s.getKeySuffixes(srcs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, suffix := range suffixes {
+		delete(s.misses, suffix)
+	}
+	return nil
+}
+
+// isMissing reports whether the model with the key suffix was missing in the loader within the negative TTL.
+func (s *readThroughStore[T]) isMissing(suffix string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.misses[suffix]
+	if !ok {
+		return false
+	}
+	if time.Now().Before(expiresAt) {
+		return true
+	}
+	delete(s.misses, suffix)
+	return false
+}
+
+// load loads models with key suffixes, and returns found models by their key suffixes.
+// Key suffixes which are being loaded by other goroutines are not loaded again, and their results are shared.
+func (s *readThroughStore[T]) load(ctx context.Context, suffixes []string) (map[string]T, error) {
+	if len(suffixes) == 0 {
+		return nil, nil
+	}
+
+	calls := make(map[string]*loadCall[T], len(suffixes))
+	owned := map[string]*loadCall[T]{}
+	s.mu.Lock()
+	for _, suffix := range suffixes {
+		c, ok := s.calls[suffix]
+		if !ok {
+			c = &loadCall[T]{done: make(chan struct{})}
+			s.calls[suffix] = c
+			owned[suffix] = c
+		}
+		calls[suffix] = c
+	}
+	s.mu.Unlock()
+
+	if len(owned) > 0 {
+		// the load is shared with other callers, so it should not fail when this caller gives up
+		go s.doLoad(detachedContext{ctx}, owned)
+	}
+
+	loaded := make(map[string]T, len(calls))
+	for suffix, c := range calls {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		}
+		if c.err != nil {
+			return nil, errors.Wrapf(c.err, "failed to load %s", suffix)
+		}
+		if c.found {
+			loaded[suffix] = c.model
+		}
+	}
+	return loaded, nil
+}
+
+// doLoad loads models of the calls with the loader, puts found ones to the store, and completes the calls.
+func (s *readThroughStore[T]) doLoad(ctx context.Context, calls map[string]*loadCall[T]) {
+	err := s.loadAndPut(ctx, calls)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err == nil && s.NegativeTTL > 0 {
+		for suffix, expiresAt := range s.misses {
+			if !now.Before(expiresAt) {
+				delete(s.misses, suffix)
+			}
+		}
+	}
+	for suffix, c := range calls {
+		c.err = err
+		if err == nil && !c.found && s.NegativeTTL > 0 {
+			s.misses[suffix] = now.Add(s.NegativeTTL)
+		}
+		delete(s.calls, suffix)
+		close(c.done)
+	}
+}
+
+func (s *readThroughStore[T]) loadAndPut(ctx context.Context, calls map[string]*loadCall[T]) error {
+	suffixes := make([]string, 0, len(calls))
+	for suffix := range calls {
+		suffixes = append(suffixes, suffix)
+	}
+
+	models, err := s.loader.Load(ctx, suffixes)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(models) == 0 {
+		return nil
+	}
+
+	loadedSuffixes, err := s.getKeySuffixes(models)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if s.LoadedTTL > 0 {
+		p, ok := s.TypedStore.(ttlPutter[T])
+		if !ok || !p.isExpirable() {
+			return errors.WithStack(ErrLoadedTTLUnsupported)
+		}
+		err = p.putWithTTL(ctx, s.LoadedTTL, models...)
+	} else {
+		err = s.TypedStore.Put(ctx, models...)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to put loaded models")
+	}
+
+	for i, suffix := range loadedSuffixes {
+		if c, ok := calls[suffix]; ok {
+			c.model = models[i]
+			c.found = true
+		}
+	}
+	return nil
+}
+
+func (s *readThroughStore[T]) getKeySuffixes(models []T) ([]string, error) {
+	suffixes := make([]string, len(models))
+	for i := range models {
+		var m interface{} = models[i]
+		if reflect.TypeOf(m).Kind() != reflect.Ptr {
+			m = &models[i]
+		}
+		var err error
+		suffixes[i], err = getKeySuffix(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get key")
+		}
+	}
+	return suffixes, nil
+}
+
+// detachedContext is a context which carries values of the parent context without its deadline and cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
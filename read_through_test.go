@@ -0,0 +1,379 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+type postLoader struct {
+	posts map[string]*rotesting.Post
+	err   error
+	calls int32
+	block chan struct{}
+}
+
+func (l *postLoader) Load(ctx context.Context, suffixes []string) ([]*rotesting.Post, error) {
+	atomic.AddInt32(&l.calls, 1)
+	if l.block != nil {
+		<-l.block
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	var posts []*rotesting.Post
+	for _, suffix := range suffixes {
+		if p, ok := l.posts[suffix]; ok {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+func newPostLoader(ids ...uint64) *postLoader {
+	l := &postLoader{posts: map[string]*rotesting.Post{}}
+	for _, id := range ids {
+		l.posts[strconv.FormatUint(id, 10)] = &rotesting.Post{ID: id, Title: "post " + strconv.FormatUint(id, 10), UpdatedAt: int64(id)}
+	}
+	return l
+}
+
+func TestReadThroughStore_Get(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	loader := newPostLoader(2, 3)
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader)
+
+	cached := &rotesting.Post{ID: 1, Title: "post 1", UpdatedAt: 1}
+	err := cache.Put(context.TODO(), cached)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	posts, err := cache.Get(context.TODO(), "3", "1", "2")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := posts, []*rotesting.Post{loader.posts["3"], cached, loader.posts["2"]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(1); got != want {
+		t.Errorf("Load() was called %d times, want %d", got, want)
+	}
+
+	posts, err = store.Get(context.TODO(), "2", "3")
+	if err != nil {
+		t.Errorf("Loaded models should be put back: %v", err)
+	}
+	if got, want := posts, []*rotesting.Post{loader.posts["2"], loader.posts["3"]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored models are %v, want %v", got, want)
+	}
+
+	_, err = cache.Get(context.TODO(), "1", "2", "3")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(1); got != want {
+		t.Errorf("Load() was called %d times, want %d", got, want)
+	}
+
+	cnt, err := cache.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 3; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestReadThroughStore_Get_WhenNotFound(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	loader := newPostLoader(1)
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader)
+
+	posts, err := cache.Get(context.TODO(), "1", "2")
+	var nfErr *ro.NotFoundError
+	if !errors.As(err, &nfErr) {
+		t.Fatalf("Get() returned %v, want a NotFoundError", err)
+	}
+	if got, want := nfErr.Suffixes, []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NotFoundError.Suffixes is %v, want %v", got, want)
+	}
+	if got, want := posts, []*rotesting.Post{loader.posts["1"]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+
+	_, err = cache.Get(context.TODO(), "2")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Get() returned %v, want ErrNotFound", err)
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(2); got != want {
+		t.Errorf("Load() was called %d times, want %d", got, want)
+	}
+}
+
+func TestReadThroughStore_Get_WithNegativeTTL(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	loader := newPostLoader()
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader, ro.WithNegativeTTL(50*time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get(context.TODO(), "1")
+		if !errors.Is(err, ro.ErrNotFound) {
+			t.Errorf("Get() returned %v, want ErrNotFound", err)
+		}
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(1); got != want {
+		t.Errorf("Load() was called %d times, want %d", got, want)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	_, err := cache.Get(context.TODO(), "1")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Get() returned %v, want ErrNotFound", err)
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(2); got != want {
+		t.Errorf("Load() was called %d times after the negative TTL, want %d", got, want)
+	}
+
+	post := &rotesting.Post{ID: 1, Title: "post 1"}
+	err = cache.Put(context.TODO(), post)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	posts, err := cache.Get(context.TODO(), "1")
+	if err != nil {
+		t.Errorf("Put models should not be treated as missing: %v", err)
+	}
+	if got, want := posts, []*rotesting.Post{post}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+}
+
+func TestReadThroughStore_Get_Concurrently(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	loader := newPostLoader(1)
+	loader.block = make(chan struct{})
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = cache.Get(context.TODO(), "1")
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(loader.block)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(1); got != want {
+		t.Errorf("Load() was called %d times, want %d", got, want)
+	}
+}
+
+func TestReadThroughStore_Get_WhenCallerCancels(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	block := make(chan struct{})
+	var loadErr error
+	cache := ro.NewReadThrough[*rotesting.Post](store, ro.LoaderFunc[*rotesting.Post](func(ctx context.Context, suffixes []string) ([]*rotesting.Post, error) {
+		<-block
+		loadErr = ctx.Err()
+		return []*rotesting.Post{{ID: 1, Title: "post 1"}}, nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := cache.Get(ctx, "1")
+		canceled <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	waiting := make(chan error)
+	go func() {
+		_, err := cache.Get(context.TODO(), "1")
+		waiting <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() returned %v, want %v", err, context.Canceled)
+	}
+
+	close(block)
+	if err := <-waiting; err != nil {
+		t.Errorf("Other callers should not be affected by the canceled caller: %v", err)
+	}
+	if loadErr != nil {
+		t.Errorf("Load() was called with a canceled context: %v", loadErr)
+	}
+}
+
+func TestReadThroughStore_WithLoadedTTL(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithTTL(time.Hour))
+	loader := newPostLoader(1)
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader, ro.WithLoadedTTL(time.Minute))
+
+	_, err := cache.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = cache.Put(context.TODO(), &rotesting.Post{ID: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	for _, c := range []struct {
+		key string
+		ttl time.Duration
+	}{
+		{key: "Post:1", ttl: time.Minute},
+		{key: "Post:2", ttl: time.Hour},
+	} {
+		ttl, err := redis.Int64(conn.Do("PTTL", c.key))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := time.Duration(ttl) * time.Millisecond; got <= c.ttl-time.Second || got > c.ttl {
+			t.Errorf("TTL of %s is %v, want %v", c.key, got, c.ttl)
+		}
+	}
+}
+
+func TestReadThroughStore_WithLoadedTTL_WhenExpired(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithTTL(time.Hour))
+	loader := newPostLoader(1)
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader, ro.WithLoadedTTL(time.Millisecond))
+
+	_, err := cache.Get(context.TODO(), "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = cache.Put(context.TODO(), &rotesting.Post{ID: 2, UpdatedAt: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	cnt, err := cache.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+
+	swept, err := cache.Sweep(context.TODO())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := swept, 1; got != want {
+		t.Errorf("Sweep() returned %d, want %d", got, want)
+	}
+}
+
+func TestReadThroughStore_WithLoadedTTL_WithoutStoreTTL(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	loader := newPostLoader(1)
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader, ro.WithLoadedTTL(time.Minute))
+
+	_, err := cache.Get(context.TODO(), "1")
+	if !errors.Is(err, ro.ErrLoadedTTLUnsupported) {
+		t.Errorf("Get() returned %v, want ErrLoadedTTLUnsupported", err)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 0; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestReadThroughStore_Get_WhenLoaderFails(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	loader := newPostLoader()
+	loader.err = errors.New("connection refused")
+	cache := ro.NewReadThrough[*rotesting.Post](store, loader, ro.WithNegativeTTL(time.Minute))
+
+	_, err := cache.Get(context.TODO(), "1")
+	if !errors.Is(err, loader.err) {
+		t.Errorf("Get() returned %v, want %v", err, loader.err)
+	}
+
+	loader.err = nil
+	_, err = cache.Get(context.TODO(), "1")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Failed loads should not be cached, Get() returned %v", err)
+	}
+	if got, want := atomic.LoadInt32(&loader.calls), int32(2); got != want {
+		t.Errorf("Load() was called %d times, want %d", got, want)
+	}
+}
+
+func TestReadThroughStore_WithValues(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[ValuePost](pool)
+	cache := ro.NewReadThrough[ValuePost](store, ro.LoaderFunc[ValuePost](func(ctx context.Context, suffixes []string) ([]ValuePost, error) {
+		posts := make([]ValuePost, len(suffixes))
+		for i, suffix := range suffixes {
+			id, _ := strconv.ParseUint(suffix, 10, 64)
+			posts[i] = ValuePost{ID: id, Title: "post " + suffix}
+		}
+		return posts, nil
+	}))
+
+	posts, err := cache.Get(context.TODO(), "2", "1")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := posts, []ValuePost{{ID: 2, Title: "post 2"}, {ID: 1, Title: "post 1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+}
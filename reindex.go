@@ -0,0 +1,356 @@
+package ro
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// reindexBatchSize is the default number of keys requested by a SCAN in Reindex.
+const reindexBatchSize = 100
+
+// ReindexOptions specifies how Reindex scans stored models.
+type ReindexOptions struct {
+	// BatchSize is the number of keys requested by a SCAN, and models found by it are reindexed in a transaction (default: 100).
+	BatchSize int
+	// Interval is a pause between batches, which throttles Reindex against live instances.
+	Interval time.Duration
+	// Progress is called after each batch with the progress so far.
+	Progress func(ReindexProgress)
+}
+
+// ReindexProgress is progress of Reindex.
+type ReindexProgress struct {
+	// Scanned is the number of keys returned by SCAN, which can contain duplicates.
+	Scanned int
+	// Reindexed is the number of reindexed models.
+	Reindexed int
+	// Skipped is the number of scanned keys which are not reindexed, e.g. bookkeeping keys of models, keys removed during Reindex or not written by the store.
+	Skipped int
+}
+
+// Reindex implements the types.Store interface.
+func (s *redisStore) Reindex(ctx context.Context, opts ReindexOptions) (int, error) {
+	cnt, err := s.intercept(ctx, &Operation{Name: "Reindex"}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return s.doReindex(ctx, opts)
+	})
+	n, _ := cnt.(int)
+	return n, err
+}
+
+// doReindex scans stored models and rebuilds their score set and lex set memberships, and returns the number of reindexed models.
+// Models themselves and their expirations are not changed, so no changes are recorded.
+func (s *redisStore) doReindex(ctx context.Context, opts ReindexOptions) (int, error) {
+	if !s.HashStoreEnabled {
+		return 0, errors.New("Reindex requires the hash store")
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = reindexBatchSize
+	}
+	pattern := escapeGlob(s.getKeyPrefix()+s.KeyDelimiter) + "*"
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	var progress ReindexProgress
+	err = scanAll(ctx, conn, pattern, batchSize, opts.Interval, func(keys []string) error {
+		scanned := len(keys)
+		progress.Scanned += scanned
+
+		keys = s.rejectBookkeepingKeys(keys)
+		var (
+			n   int
+			err error
+		)
+		for i := 0; i < txRetryLimit; i++ {
+			n, err = s.reindex(conn, keys)
+			if errors.Cause(err) != errTxAborted {
+				break
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to reindex %v", keys)
+		}
+		progress.Reindexed += n
+		progress.Skipped += scanned - n
+
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		return nil
+	})
+	return progress.Reindexed, errors.WithStack(err)
+}
+
+// scanAll calls fn with keys returned by each SCAN matching the pattern, and pauses for interval between them.
+func scanAll(ctx context.Context, conn Conn, pattern string, count int, interval time.Duration, fn func(keys []string) error) error {
+	return iterateCursor(ctx, interval, func(cursor string) (string, error) {
+		next, keys, err := scanKeys(conn, cursor, pattern, count)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return next, fn(keys)
+	})
+}
+
+// iterateCursor calls next with cursors returned by it until it returns 0, and pauses for interval between calls.
+func iterateCursor(ctx context.Context, interval time.Duration, next func(cursor string) (string, error)) error {
+	cursor := "0"
+	for {
+		var err error
+		cursor, err = next(cursor)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if cursor == "0" {
+			return nil
+		}
+
+		err = sleepContext(ctx, interval)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// scanKeys executes SCAN, and returns the next cursor and keys.
+func scanKeys(conn Conn, cursor, pattern string, count int) (string, []string, error) {
+	reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", count))
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "faild to execute SCAN %s MATCH %s", cursor, pattern)
+	}
+	if len(reply) != 2 {
+		return "", nil, errors.Errorf("unexpected SCAN reply %v", reply)
+	}
+	next, err := redis.String(reply[0], nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "faild to cast a cursor of SCAN")
+	}
+	keys, err := redis.Strings(reply[1], nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "faild to cast keys of SCAN")
+	}
+	return next, keys, nil
+}
+
+// rejectBookkeepingKeys filters out keys written by the store other than models, which are scanned with models.
+func (s *redisStore) rejectBookkeepingKeys(keys []string) []string {
+	scoreSetKeysKeySuffix := s.KeyDelimiter + s.ScoreSetKeysKeySuffix
+	lexSetMembersKeySuffix := s.KeyDelimiter + s.LexSetMembersKeySuffix
+	filtered := keys[:0]
+	for _, k := range keys {
+		if !strings.HasSuffix(k, scoreSetKeysKeySuffix) && !strings.HasSuffix(k, lexSetMembersKeySuffix) {
+			filtered = append(filtered, k)
+		}
+	}
+	return filtered
+}
+
+// reindex rebuilds memberships of models stored with keys in a transaction, and returns the number of reindexed models.
+// Models and their bookkeeping keys are watched, so models changed concurrently are read again by a retry.
+func (s *redisStore) reindex(conn Conn, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	err := s.watchModels(conn, keys)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	entries, err := s.createReindexEntries(conn, keys)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return 0, errors.WithStack(err)
+	}
+	if len(entries) == 0 {
+		conn.Do("UNWATCH")
+		return 0, nil
+	}
+
+	zsetKeysByKey, lexMembersByKey, err := s.readIndexes(conn, entries)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return 0, errors.WithStack(err)
+	}
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		conn.Do("UNWATCH")
+		return 0, errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	err = s.sendIndexes(conn, entries, zsetKeysByKey, lexMembersByKey)
+	if err != nil {
+		conn.Do("DISCARD")
+		return 0, errors.Wrap(err, "faild to send any commands")
+	}
+
+	reply, err := conn.Do("EXEC")
+	if err != nil {
+		return 0, errors.Wrap(err, "faild to EXEC commands")
+	}
+	if reply == nil {
+		return 0, errTxAborted
+	}
+	return len(entries), nil
+}
+
+// watchModels watches models stored with keys and their bookkeeping keys.
+func (s *redisStore) watchModels(conn Conn, keys []string) error {
+	watchedKeys := redis.Args{}.AddFlat(keys)
+	for _, k := range keys {
+		watchedKeys = watchedKeys.Add(s.getScoreSetKeysKeyByKey(k))
+		if s.isLexIndexed() {
+			watchedKeys = watchedKeys.Add(s.getLexSetMembersKeyByKey(k))
+		}
+	}
+	_, err := conn.Do("WATCH", watchedKeys...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to execute WATCH %v", watchedKeys)
+	}
+	return nil
+}
+
+// readIndexes returns score set keys and lex set members recorded for entries, which are keyed by their bookkeeping keys.
+func (s *redisStore) readIndexes(conn Conn, entries []*putEntry) (map[string][]string, map[string]map[string]string, error) {
+	scoreSetKeysKeys := make([]string, len(entries))
+	var lexSetMembersKeys []string
+	for i, e := range entries {
+		scoreSetKeysKeys[i] = s.getScoreSetKeysKeyByKey(e.key)
+		if s.isLexIndexed() {
+			lexSetMembersKeys = append(lexSetMembersKeys, s.getLexSetMembersKeyByKey(e.key))
+		}
+	}
+
+	zsetKeysByKey, err := s.getScoreSetKeysByKeys(conn, scoreSetKeysKeys)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	lexMembersByKey, err := s.getLexMembersByKeys(conn, lexSetMembersKeys)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	return zsetKeysByKey, lexMembersByKey, nil
+}
+
+// sendIndexes sends commands rebuilding memberships of entries from recorded ones read by readIndexes.
+func (s *redisStore) sendIndexes(conn Conn, entries []*putEntry, zsetKeysByKey map[string][]string, lexMembersByKey map[string]map[string]string) error {
+	for _, e := range entries {
+		err := s.setScores(conn, e, zsetKeysByKey[s.getScoreSetKeysKeyByKey(e.key)])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = s.setLexMembers(conn, e, lexMembersByKey[s.getLexSetMembersKeyByKey(e.key)])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// createReindexEntries reads models stored with keys, and creates entries of models producing the same keys.
+// Keys removed after they were scanned and keys of other types than models are ignored.
+func (s *redisStore) createReindexEntries(conn Conn, keys []string) ([]*putEntry, error) {
+	valueType := "hash"
+	if s.Codec != nil {
+		valueType = "string"
+	}
+	keys, err := selectKeysByType(conn, keys, valueType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	values, found, err := s.fetchValues(conn, keys, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	now := time.Now()
+	entries := make([]*putEntry, 0, len(keys))
+	for i, k := range keys {
+		if !found[i] {
+			continue
+		}
+		m := reflect.New(s.modelType).Interface()
+		err = s.scanValue(values[i], m)
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to scan struct %s", k)
+		}
+		key, err := s.getKey(m)
+		if err != nil || key != k {
+			continue
+		}
+		e, err := s.createPutEntry(m, now)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// selectKeysByType returns keys storing values of the type, which is a reply of TYPE.
+func selectKeysByType(conn Conn, keys []string, valueType string) ([]string, error) {
+	for _, k := range keys {
+		err := conn.Send("TYPE", k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to send TYPE %s", k)
+		}
+	}
+	err := conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush TYPE commands")
+	}
+
+	selected := make([]string, 0, len(keys))
+	for _, k := range keys {
+		t, err := redis.String(conn.Receive())
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to execute TYPE %s", k)
+		}
+		if t == valueType {
+			selected = append(selected, k)
+		}
+	}
+	return selected, nil
+}
+
+// escapeGlob escapes special characters of glob-style patterns in the string.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// sleepContext pauses for d, and returns an error when ctx is done before that.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
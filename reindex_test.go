@@ -0,0 +1,241 @@
+package ro_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+)
+
+type ReindexedPostV1 struct {
+	ID        uint64 `redis:"id" ro:"key"`
+	UserID    uint64 `redis:"user_id" ro:"score=user:{UserID},value=CreatedAt"`
+	CreatedAt int64  `redis:"created_at" ro:"score=recent"`
+}
+
+type ReindexedPostV2 struct {
+	ID        uint64 `redis:"id" ro:"key;score=id"`
+	UserID    uint64 `redis:"user_id"`
+	CreatedAt int64  `redis:"created_at" ro:"score=recent,value=ID"`
+}
+
+func TestRedisStore_Reindex(t *testing.T) {
+	defer teardown(t)
+
+	v1 := ro.New(pool, &ReindexedPostV1{}, ro.WithKeyPrefix("Post"), ro.WithChangeFeed(true))
+	err := v1.Put(context.TODO(), []*ReindexedPostV1{
+		{ID: 1, UserID: 1, CreatedAt: 300},
+		{ID: 2, UserID: 1, CreatedAt: 200},
+		{ID: 3, UserID: 2, CreatedAt: 100},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	// a hash which is not written by the store
+	_, err = conn.Do("HSET", "Post:stray", "id", 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	v2 := ro.New(pool, &ReindexedPostV2{}, ro.WithKeyPrefix("Post"), ro.WithChangeFeed(true))
+	var progress ro.ReindexProgress
+	n, err := v2.Reindex(context.TODO(), ro.ReindexOptions{
+		BatchSize: 2,
+		Interval:  time.Millisecond,
+		Progress:  func(p ro.ReindexProgress) { progress = p },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := n, 3; got != want {
+		t.Errorf("Reindex() returned %d, want %d", got, want)
+	}
+	if got, want := progress.Reindexed, 3; got != want {
+		t.Errorf("ReindexProgress.Reindexed is %d, want %d", got, want)
+	}
+	// Post:{1,2,3}:scoreSetKeys and Post:stray are skipped
+	if got, want := progress.Skipped, 4; got != want {
+		t.Errorf("ReindexProgress.Skipped is %d, want %d", got, want)
+	}
+	if got, want := progress.Scanned, 7; got != want {
+		t.Errorf("ReindexProgress.Scanned is %d, want %d", got, want)
+	}
+
+	got := []*ReindexedPostV2{}
+	err = v2.List(context.TODO(), &got, rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []*ReindexedPostV2{
+		{ID: 1, UserID: 1, CreatedAt: 300},
+		{ID: 2, UserID: 1, CreatedAt: 200},
+		{ID: 3, UserID: 2, CreatedAt: 100},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	cnt, err := v2.Count(context.TODO(), rq.Key("id"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 3; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+
+	exists, err := redis.Bool(conn.Do("EXISTS", "Post/user:1", "Post/user:2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exists {
+		t.Error("Memberships no longer produced by models should be removed")
+	}
+
+	zsetKeys, err := redis.Strings(conn.Do("SMEMBERS", "Post:1:scoreSetKeys"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(zsetKeys)
+	if got, want := zsetKeys, []string{"Post/id", "Post/recent"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Post:1:scoreSetKeys contains %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Reindex_WithOtherTypes(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &ReindexedPostV2{}, ro.WithKeyPrefix("Post"))
+	err := store.Put(context.TODO(), []*ReindexedPostV2{{ID: 1, UserID: 1, CreatedAt: 100}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	// keys of other types which are not written by the store
+	_, err = conn.Do("SET", "Post:note", "hello")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = conn.Do("RPUSH", "Post:queue", 1, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = conn.Do("DEL", "Post/recent")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var progress ro.ReindexProgress
+	n, err := store.Reindex(context.TODO(), ro.ReindexOptions{
+		Progress: func(p ro.ReindexProgress) { progress = p },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("Reindex() returned %d, want %d", got, want)
+	}
+	if got, want := progress.Skipped, progress.Scanned-1; got != want {
+		t.Errorf("ReindexProgress.Skipped is %d, want %d", got, want)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestRedisStore_Reindex_WithLex(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	err := store.Put(context.TODO(), []*Author{{ID: 1, Name: "bob"}, {ID: 2, Name: "alice"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("DEL", "Author/id", "Author/name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	n, err := store.Reindex(context.TODO(), ro.ReindexOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("Reindex() returned %d, want %d", got, want)
+	}
+
+	got := []*Author{}
+	err = store.List(context.TODO(), &got, rq.Key("name"), rq.LexGtEq("b"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []*Author{{ID: 1, Name: "bob"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+}
+
+func TestTypedStore_Reindex_WithCodec(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*Article](pool, ro.WithCodec(ro.JSONCodec))
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := store.Put(context.TODO(), &Article{ID: 1, Author: Author{ID: 1}, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("DEL", "Article/recent")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	n, err := store.Reindex(context.TODO(), ro.ReindexOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("Reindex() returned %d, want %d", got, want)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestRedisStore_Reindex_WithoutHashStore(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &ReindexedPostV2{}, ro.WithHashStore(false))
+	_, err := store.Reindex(context.TODO(), ro.ReindexOptions{})
+	if err == nil {
+		t.Error("Reindex() should return an error without the hash store")
+	}
+}
@@ -0,0 +1,32 @@
+package rq
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Cursor points a position in a sorted set with a score and a member.
+type Cursor struct {
+	Score  string
+	Member string
+}
+
+// String returns an opaque string representation of the cursor.
+func (c *Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Score + ":" + c.Member))
+}
+
+// ParseCursor decodes a string created by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cursor %q", s)
+	}
+	tokens := strings.SplitN(string(b), ":", 2)
+	if len(tokens) != 2 || tokens[0] == "" {
+		return nil, errors.Errorf("invalid cursor %q", s)
+	}
+	return &Cursor{Score: tokens[0], Member: tokens[1]}, nil
+}
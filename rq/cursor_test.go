@@ -0,0 +1,42 @@
+package rq_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/izumin5210/ro/rq"
+)
+
+func TestCursor(t *testing.T) {
+	cases := []*rq.Cursor{
+		{Score: "10", Member: "foo:1"},
+		{Score: "-1.5e+18", Member: "foo:bar:1"},
+		{Score: "0", Member: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Score+" "+c.Member, func(t *testing.T) {
+			got, err := rq.ParseCursor(c.String())
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if want := c; !reflect.DeepEqual(got, want) {
+				t.Errorf("returned %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseCursor_WithInvalidString(t *testing.T) {
+	for _, s := range []string{"!!", "Zm9v", ""} {
+		t.Run(s, func(t *testing.T) {
+			c, err := rq.ParseCursor(s)
+			if err == nil {
+				t.Error("should return an error")
+			}
+			if c != nil {
+				t.Errorf("returned %v, want nil", c)
+			}
+		})
+	}
+}
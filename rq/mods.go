@@ -4,6 +4,9 @@ import (
 	"fmt"
 )
 
+// LexMaxByte is greater than any bytes in UTF-8 strings, and it is used for an upper bound of a prefix range.
+const LexMaxByte = "\xff"
+
 var (
 	// DefaultKeyDelimiter is used to join tokens for key parameters in default.
 	DefaultKeyDelimiter = ":"
@@ -85,3 +88,123 @@ func Reverse() Modifier {
 		q.Reverse = !q.Reverse
 	}
 }
+
+// After specifies a cursor which returned values follow in the order of a query.
+// The cursor takes priority over a value range for scores in the paging direction.
+// An empty cursor points the beginning of a query. Cursors can be used only with ListPage.
+func After(cursor string) Modifier {
+	return func(q *Query) {
+		q.Cursor = cursor
+		q.Backward = false
+	}
+}
+
+// Before specifies a cursor which returned values precede in the order of a query.
+// The cursor takes priority over a value range for scores in the paging direction.
+// An empty cursor points the end of a query. Cursors can be used only with ListPage.
+func Before(cursor string) Modifier {
+	return func(q *Query) {
+		q.Cursor = cursor
+		q.Backward = true
+	}
+}
+
+// LexGt specifies a lexicographical range with `>` for members of a query.
+func LexGt(v string) Modifier {
+	return func(q *Query) {
+		q.LexMin = "(" + v
+	}
+}
+
+// LexGtEq specifies a lexicographical range with `>=` for members of a query.
+func LexGtEq(v string) Modifier {
+	return func(q *Query) {
+		q.LexMin = "[" + v
+	}
+}
+
+// LexLt specifies a lexicographical range with `<` for members of a query.
+func LexLt(v string) Modifier {
+	return func(q *Query) {
+		q.LexMax = "(" + v
+	}
+}
+
+// LexLtEq specifies a lexicographical range with `<=` for members of a query.
+func LexLtEq(v string) Modifier {
+	return func(q *Query) {
+		q.LexMax = "[" + v
+	}
+}
+
+// LexEq specifies a lexicographical range with `=` for members of a query.
+func LexEq(v string) Modifier {
+	return func(q *Query) {
+		LexGtEq(v)(q)
+		LexLtEq(v)(q)
+	}
+}
+
+// LexPrefix specifies a lexicographical range of members starting with the prefix.
+func LexPrefix(prefix string) Modifier {
+	return func(q *Query) {
+		q.LexMin = "[" + prefix
+		q.LexMax = "[" + prefix + LexMaxByte
+	}
+}
+
+// SetKey contains tokens of a key for set operations, which are joined like Key.
+type SetKey []interface{}
+
+// Inter specifies an intersection of sorted sets, which a query ranges over instead of a single sorted set.
+// Query.BuildSetOp stores the intersection into a key of the query, while stores use a temporary key and reject a key of the query.
+func Inter(keys ...SetKey) Modifier {
+	return setOp(SetOperationInter, keys)
+}
+
+// Union specifies a union of sorted sets, which a query ranges over instead of a single sorted set.
+// Query.BuildSetOp stores the union into a key of the query, while stores use a temporary key and reject a key of the query.
+func Union(keys ...SetKey) Modifier {
+	return setOp(SetOperationUnion, keys)
+}
+
+func setOp(t SetOperationType, keys []SetKey) Modifier {
+	return func(q *Query) {
+		if q.SetOp == nil {
+			q.SetOp = &SetOperation{}
+		}
+		q.SetOp.Type = t
+		q.SetOp.Keys = make([]QueryKey, len(keys))
+		for i, k := range keys {
+			q.SetOp.Keys[i].Tokens = k
+		}
+	}
+}
+
+// Weights specifies multiplication factors for scores of each sorted set in a set operation.
+func Weights(weights ...float64) Modifier {
+	return func(q *Query) {
+		if q.SetOp == nil {
+			q.SetOp = &SetOperation{}
+		}
+		q.SetOp.Weights = weights
+	}
+}
+
+// AggregateBy specifies how scores of a member are combined in a set operation (default: AggregateSum).
+func AggregateBy(a Aggregate) Modifier {
+	return func(q *Query) {
+		if q.SetOp == nil {
+			q.SetOp = &SetOperation{}
+		}
+		q.SetOp.Aggregate = a
+	}
+}
+
+// Select specifies fields of models which are read by a query.
+// Stores read all fields when no fields are selected.
+func Select(fields ...string) Modifier {
+	return func(q *Query) {
+		q.Fields = append(q.Fields, fields...)
+	}
+}
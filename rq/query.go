@@ -24,6 +24,14 @@ const (
 	zrevrangeByScore = "ZREVRANGEBYSCORE"
 	zcard            = "ZCARD"
 	zcount           = "ZCOUNT"
+	withScores       = "WITHSCORES"
+	zrangeByLex      = "ZRANGEBYLEX"
+	zrevrangeByLex   = "ZREVRANGEBYLEX"
+	zlexcount        = "ZLEXCOUNT"
+	zinterstore      = "ZINTERSTORE"
+	zunionstore      = "ZUNIONSTORE"
+	lexInf           = "+"
+	lexNeginf        = "-"
 	inf              = "+inf"
 	neginf           = "-inf"
 )
@@ -84,13 +92,99 @@ func (q *QueryKey) Build() (string, error) {
 
 // Query contains parameters to build a redis command.
 type Query struct {
-	Type    CommandType
-	Key     QueryKey
-	Min     interface{}
-	Max     interface{}
-	Limit   int
-	Offset  int
-	Reverse bool
+	Type       CommandType
+	Key        QueryKey
+	Min        interface{}
+	Max        interface{}
+	Limit      int
+	Offset     int
+	Reverse    bool
+	Cursor     string
+	Backward   bool
+	WithScores bool
+	LexMin     string
+	LexMax     string
+	SetOp      *SetOperation
+	Fields     []string
+}
+
+// SetOperationType represents types of operations combining sorted sets.
+type SetOperationType int
+
+// Set operation types
+const (
+	SetOperationInter SetOperationType = iota
+	SetOperationUnion
+)
+
+// Aggregate represents how scores of a member are combined in a set operation.
+type Aggregate string
+
+// Aggregate modes
+const (
+	AggregateSum Aggregate = "SUM"
+	AggregateMin Aggregate = "MIN"
+	AggregateMax Aggregate = "MAX"
+)
+
+// SetOperation contains parameters to combine sorted sets into a key of a query.
+type SetOperation struct {
+	Type      SetOperationType
+	Keys      []QueryKey
+	Weights   []float64
+	Aggregate Aggregate
+}
+
+// BuildSetOp creates a command storing a combination of sorted sets into a key of the query.
+// Commands created by Build range over the stored key.
+func (q *Query) BuildSetOp() (*Command, error) {
+	op := q.SetOp
+	if op == nil {
+		return nil, errors.WithStack(newQueryError(q, "set operation is not specified"))
+	}
+	if len(op.Keys) == 0 {
+		return nil, errors.WithStack(newQueryError(q, "keys of set operation are required"))
+	}
+	if len(op.Weights) > 0 && len(op.Weights) != len(op.Keys) {
+		return nil, errors.WithStack(newQueryError(q, "weights of set operation should be as many as keys"))
+	}
+
+	dest, err := q.Key.Build()
+	if err != nil {
+		return nil, errors.WithStack(newQueryError(q, err.Error()))
+	}
+
+	cmd := &Command{Args: make([]interface{}, 0, 2*len(op.Keys)+5)}
+	switch op.Type {
+	case SetOperationInter:
+		cmd.Name = zinterstore
+	case SetOperationUnion:
+		cmd.Name = zunionstore
+	default:
+		return nil, errors.WithStack(newQueryError(q, "unknown set operation type"))
+	}
+
+	cmd.Args = append(cmd.Args, dest, len(op.Keys))
+	for i := range op.Keys {
+		key, err := op.Keys[i].Build()
+		if err != nil {
+			return nil, errors.WithStack(newQueryError(q, err.Error()))
+		}
+		cmd.Args = append(cmd.Args, key)
+	}
+
+	if len(op.Weights) > 0 {
+		cmd.Args = append(cmd.Args, "WEIGHTS")
+		for _, w := range op.Weights {
+			cmd.Args = append(cmd.Args, w)
+		}
+	}
+
+	if op.Aggregate != "" {
+		cmd.Args = append(cmd.Args, "AGGREGATE", string(op.Aggregate))
+	}
+
+	return cmd, nil
 }
 
 // Build decide a redis command and args from query parameters.
@@ -119,13 +213,39 @@ func (q *Query) buildListCommand() (*Command, error) {
 		return nil, errors.WithStack(newQueryError(q, err.Error()))
 	}
 
+	err = q.validateCursor()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = q.validateLex()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	cmd := &Command{Args: make([]interface{}, 1, 10)}
 	cmd.Args[0] = key
 
-	if q.isWithScore() {
+	reverse := q.Reverse
+
+	if q.IsLex() {
+		min, max := q.getLexMinAndMax()
+
+		if reverse {
+			cmd.Name = zrevrangeByLex
+			cmd.Args = append(cmd.Args, max, min)
+		} else {
+			cmd.Name = zrangeByLex
+			cmd.Args = append(cmd.Args, min, max)
+		}
+
+		if q.Offset != 0 || q.Limit != -1 {
+			cmd.Args = append(cmd.Args, "LIMIT", q.Offset, q.Limit)
+		}
+	} else if q.isWithScore() {
 		min, max := q.getMinAndMax()
 
-		if q.Reverse {
+		if reverse {
 			cmd.Name = zrevrangeByScore
 			cmd.Args = append(cmd.Args, max, min)
 		} else {
@@ -133,11 +253,15 @@ func (q *Query) buildListCommand() (*Command, error) {
 			cmd.Args = append(cmd.Args, min, max)
 		}
 
+		if q.WithScores {
+			cmd.Args = append(cmd.Args, withScores)
+		}
+
 		if q.Offset != 0 || q.Limit != -1 {
 			cmd.Args = append(cmd.Args, "LIMIT", q.Offset, q.Limit)
 		}
 	} else {
-		if q.Reverse {
+		if reverse {
 			cmd.Name = zrevrange
 		} else {
 			cmd.Name = zrange
@@ -148,21 +272,65 @@ func (q *Query) buildListCommand() (*Command, error) {
 			end = q.Offset + q.Limit - 1
 		}
 		cmd.Args = append(cmd.Args, q.Offset, end)
+
+		if q.WithScores {
+			cmd.Args = append(cmd.Args, withScores)
+		}
 	}
 
 	return cmd, nil
 }
 
+// GetCursor returns a decoded cursor of the query, or nil when the query has no cursor.
+func (q *Query) GetCursor() (*Cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	c, err := ParseCursor(q.Cursor)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return c, nil
+}
+
+// IsReversed reports whether a page of the query is selected in descending order.
+// Paging backward flips the order of the query.
+func (q *Query) IsReversed() bool {
+	return q.Reverse != q.Backward
+}
+
+// validateCursor rejects cursors, which are handled by ListPage since commands cannot skip members at the cursor position.
+func (q *Query) validateCursor() error {
+	if q.Cursor != "" || q.Backward {
+		return newQueryError(q, "cursors can be used only with ListPage")
+	}
+	return nil
+}
+
 func (q *Query) buildCountCommand() (*Command, error) {
 	key, err := q.Key.Build()
 	if err != nil {
 		return nil, errors.WithStack(newQueryError(q, err.Error()))
 	}
 
+	err = q.validateCursor()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = q.validateLex()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	cmd := &Command{Name: zcard, Args: make([]interface{}, 1, 10)}
 	cmd.Args[0] = key
 
-	if q.isWithScore() {
+	if q.IsLex() {
+		cmd.Name = zlexcount
+		min, max := q.getLexMinAndMax()
+		cmd.Args = append(cmd.Args, min, max)
+	} else if q.isWithScore() {
 		cmd.Name = zcount
 		min, max := q.getMinAndMax()
 		cmd.Args = append(cmd.Args, min, max)
@@ -171,6 +339,35 @@ func (q *Query) buildCountCommand() (*Command, error) {
 	return cmd, nil
 }
 
+// IsLex reports whether the query has a lexicographical range.
+func (q *Query) IsLex() bool {
+	return q.LexMin != "" || q.LexMax != ""
+}
+
+func (q *Query) validateLex() error {
+	if !q.IsLex() {
+		return nil
+	}
+	if q.isWithScore() {
+		return newQueryError(q, "lexicographical ranges cannot be used with score ranges")
+	}
+	if q.Type == CommandList && q.WithScores {
+		return newQueryError(q, "lexicographical ranges cannot be used with scores")
+	}
+	return nil
+}
+
+func (q *Query) getLexMinAndMax() (string, string) {
+	min, max := q.LexMin, q.LexMax
+	if min == "" {
+		min = lexNeginf
+	}
+	if max == "" {
+		max = lexInf
+	}
+	return min, max
+}
+
 func (q *Query) isWithScore() bool {
 	return q.Min != nil || q.Max != nil
 }
@@ -8,6 +8,8 @@ import (
 )
 
 func TestQuery_Build(t *testing.T) {
+	cursor := (&rq.Cursor{Score: "10", Member: "foo:1"}).String()
+
 	cases := []struct {
 		test  string
 		build func(...rq.Modifier) *rq.Query
@@ -150,6 +152,87 @@ func TestQuery_Build(t *testing.T) {
 			mods:  []rq.Modifier{rq.Key("foo"), rq.GtEq(6), rq.LtEq(10)},
 			cmd:   &rq.Command{Name: "ZCOUNT", Args: []interface{}{"foo", 6, 10}},
 		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.Limit(10), func(q *rq.Query) { q.WithScores = true }},
+			cmd:   &rq.Command{Name: "ZRANGE", Args: []interface{}{"foo", 0, 9, "WITHSCORES"}},
+		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.Gt(5), rq.Limit(10), func(q *rq.Query) { q.WithScores = true }},
+			cmd:   &rq.Command{Name: "ZRANGEBYSCORE", Args: []interface{}{"foo", "(5", "+inf", "WITHSCORES", "LIMIT", 0, 10}},
+		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexGtEq("bar")},
+			cmd:   &rq.Command{Name: "ZRANGEBYLEX", Args: []interface{}{"foo", "[bar", "+"}},
+		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexLt("bar")},
+			cmd:   &rq.Command{Name: "ZRANGEBYLEX", Args: []interface{}{"foo", "-", "(bar"}},
+		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexGt("bar"), rq.LexLtEq("baz"), rq.Reverse()},
+			cmd:   &rq.Command{Name: "ZREVRANGEBYLEX", Args: []interface{}{"foo", "[baz", "(bar"}},
+		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexEq("bar"), rq.Limit(10)},
+			cmd:   &rq.Command{Name: "ZRANGEBYLEX", Args: []interface{}{"foo", "[bar", "[bar", "LIMIT", 0, 10}},
+		},
+		{
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexPrefix("ba"), rq.Offset(5)},
+			cmd:   &rq.Command{Name: "ZRANGEBYLEX", Args: []interface{}{"foo", "[ba", "[ba\xff", "LIMIT", 5, -1}},
+		},
+		{
+			build: rq.Count,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexPrefix("ba")},
+			cmd:   &rq.Command{Name: "ZLEXCOUNT", Args: []interface{}{"foo", "[ba", "[ba\xff"}},
+		},
+		{
+			build: rq.Count,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexLt("bar")},
+			cmd:   &rq.Command{Name: "ZLEXCOUNT", Args: []interface{}{"foo", "-", "(bar"}},
+		},
+		{
+			test:  "with lex and score ranges",
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexGt("bar"), rq.Gt(10)},
+			isErr: true,
+		},
+		{
+			test:  "with a cursor",
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.After(cursor), rq.Limit(10)},
+			isErr: true,
+		},
+		{
+			test:  "with a backward cursor",
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.Before(""), rq.Limit(10)},
+			isErr: true,
+		},
+		{
+			test:  "count with a cursor",
+			build: rq.Count,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.After(cursor)},
+			isErr: true,
+		},
+		{
+			test:  "with lex range and cursor",
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.LexGt("bar"), rq.After(cursor)},
+			isErr: true,
+		},
+		{
+			test:  "with an invalid cursor",
+			build: rq.List,
+			mods:  []rq.Modifier{rq.Key("foo"), rq.After("!!")},
+			isErr: true,
+		},
 		{
 			test:  "without key",
 			build: rq.List,
@@ -192,3 +275,73 @@ func TestQuery_Build(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_BuildSetOp(t *testing.T) {
+	cases := []struct {
+		test  string
+		mods  []rq.Modifier
+		cmd   *rq.Command
+		isErr bool
+	}{
+		{
+			mods: []rq.Modifier{rq.Key("tmp"), rq.Inter(rq.SetKey{"user", 1}, rq.SetKey{"featured"})},
+			cmd:  &rq.Command{Name: "ZINTERSTORE", Args: []interface{}{"tmp", 2, "user:1", "featured"}},
+		},
+		{
+			mods: []rq.Modifier{rq.Key("tmp"), rq.KeyPrefix("Post"), rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2}), rq.AggregateBy(rq.AggregateMax)},
+			cmd:  &rq.Command{Name: "ZUNIONSTORE", Args: []interface{}{"Post/tmp", 2, "user:1", "user:2", "AGGREGATE", "MAX"}},
+		},
+		{
+			mods: []rq.Modifier{rq.Key("tmp"), rq.Weights(1, 0.5), rq.Union(rq.SetKey{"user", 1}, rq.SetKey{"user", 2})},
+			cmd:  &rq.Command{Name: "ZUNIONSTORE", Args: []interface{}{"tmp", 2, "user:1", "user:2", "WEIGHTS", 1.0, 0.5}},
+		},
+		{
+			test:  "without set operation",
+			mods:  []rq.Modifier{rq.Key("tmp")},
+			isErr: true,
+		},
+		{
+			test:  "without keys",
+			mods:  []rq.Modifier{rq.Key("tmp"), rq.Inter()},
+			isErr: true,
+		},
+		{
+			test:  "with mismatched weights",
+			mods:  []rq.Modifier{rq.Key("tmp"), rq.Inter(rq.SetKey{"foo"}, rq.SetKey{"bar"}), rq.Weights(1)},
+			isErr: true,
+		},
+		{
+			test:  "without destination",
+			mods:  []rq.Modifier{rq.Inter(rq.SetKey{"foo"}, rq.SetKey{"bar"})},
+			isErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		test := c.test
+		if test == "" {
+			test = c.cmd.String()
+		}
+		t.Run(test, func(t *testing.T) {
+			cmd, err := rq.List(c.mods...).BuildSetOp()
+
+			if c.isErr {
+				if err == nil {
+					t.Error("should return an error")
+				}
+
+				if cmd != nil {
+					t.Errorf("returned %v, want nil", cmd)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("returned %v, want nil", err)
+				}
+
+				if got, want := cmd, c.cmd; !reflect.DeepEqual(got, want) {
+					t.Errorf("returned %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
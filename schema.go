@@ -0,0 +1,336 @@
+package ro
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// modelSchema contains metadata of a model type described by `ro` struct tags.
+// A key and scores of a model are derived from it when the model does not implement Model.
+type modelSchema struct {
+	key     *keyField
+	scores  []*scoreField
+	version *versionField
+}
+
+// keyField is a field tagged with `ro:"key"`, which is used as a key suffix.
+type keyField struct {
+	index []int
+}
+
+// scoreField is a field tagged with `ro:"score=<name>"`, which is stored into a score set named by the template.
+// The score is read from the field itself, or from a field specified with a `value` param.
+type scoreField struct {
+	name  *nameTemplate
+	value []int
+}
+
+// nameTemplate is a name of score sets which can contain field names wrapped in braces (e.g. `user:{UserID}`).
+// Literals and fields are alternated, so literals always have one more element than fields.
+type nameTemplate struct {
+	literals []string
+	fields   [][]int
+}
+
+// versionField is a field tagged with `ro:"version"`, which is used for optimistic concurrency control.
+type versionField struct {
+	index     []int
+	hashField string
+}
+
+var schemaCache sync.Map
+
+// getSchema returns a cached schema of the model type.
+func getSchema(modelType reflect.Type) (*modelSchema, error) {
+	if v, ok := schemaCache.Load(modelType); ok {
+		return v.(*modelSchema), nil
+	}
+
+	schema, err := parseSchema(modelType)
+	if err != nil {
+		return nil, err
+	}
+
+	v, _ := schemaCache.LoadOrStore(modelType, schema)
+	return v.(*modelSchema), nil
+}
+
+func parseSchema(modelType reflect.Type) (*modelSchema, error) {
+	schema := &modelSchema{}
+
+	if modelType.Kind() != reflect.Struct {
+		return schema, nil
+	}
+
+	for i := 0; i < modelType.NumField(); i++ {
+		f := modelType.Field(i)
+		for _, d := range parseTag(f.Tag.Get("ro")) {
+			switch d.name {
+			case "key":
+				if schema.key != nil {
+					return nil, fmt.Errorf("%s has multiple fields tagged with `ro:\"key\"`", modelType.Name())
+				}
+				schema.key = &keyField{index: f.Index}
+			case "score":
+				score, err := parseScoreField(modelType, f, d)
+				if err != nil {
+					return nil, err
+				}
+				schema.scores = append(schema.scores, score)
+			case "version":
+				switch f.Type.Kind() {
+				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				default:
+					return nil, fmt.Errorf("%s.%s tagged with `ro:\"version\"` should be an integer", modelType.Name(), f.Name)
+				}
+				if schema.version != nil {
+					return nil, fmt.Errorf("%s has multiple fields tagged with `ro:\"version\"`", modelType.Name())
+				}
+				schema.version = &versionField{index: f.Index, hashField: hashFieldName(f)}
+			default:
+				return nil, fmt.Errorf("%s.%s has an unknown `ro` tag directive %q", modelType.Name(), f.Name, d.name)
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+func parseScoreField(modelType reflect.Type, f reflect.StructField, d *tagDirective) (*scoreField, error) {
+	name, err := parseNameTemplate(modelType, d.value)
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s has an invalid score name %q: %v", modelType.Name(), f.Name, d.value, err)
+	}
+
+	value := f
+	if v, ok := d.params["value"]; ok {
+		value, ok = modelType.FieldByName(v)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s refers to an unknown field %q", modelType.Name(), f.Name, v)
+		}
+	}
+	switch value.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return nil, fmt.Errorf("%s.%s used as a score should be a number", modelType.Name(), value.Name)
+	}
+
+	return &scoreField{name: name, value: value.Index}, nil
+}
+
+func parseNameTemplate(modelType reflect.Type, s string) (*nameTemplate, error) {
+	if s == "" {
+		return nil, fmt.Errorf("name should be present")
+	}
+
+	t := &nameTemplate{}
+	for {
+		start := strings.Index(s, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed brace")
+		}
+		end += start
+		f, ok := modelType.FieldByName(s[start+1 : end])
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", s[start+1:end])
+		}
+		t.literals = append(t.literals, s[:start])
+		t.fields = append(t.fields, f.Index)
+		s = s[end+1:]
+	}
+	t.literals = append(t.literals, s)
+
+	return t, nil
+}
+
+// tagDirective is a directive in a `ro` struct tag.
+// Directives are separated by semicolons, and each of them is formed as `name[=value][,param=value...]`.
+type tagDirective struct {
+	name   string
+	value  string
+	params map[string]string
+}
+
+func parseTag(tag string) []*tagDirective {
+	var directives []*tagDirective
+	for _, s := range strings.Split(tag, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		tokens := strings.Split(s, ",")
+		d := &tagDirective{params: map[string]string{}}
+		d.name, d.value = splitTagPair(tokens[0])
+		for _, t := range tokens[1:] {
+			k, v := splitTagPair(t)
+			d.params[k] = v
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+func splitTagPair(s string) (string, string) {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) == 1 {
+		return strings.TrimSpace(kv[0]), ""
+	}
+	return strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+}
+
+// hashFieldName returns a name of a hash field storing the struct field, which follows `redis` struct tags.
+func hashFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("redis"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// lookupFields returns struct fields of the model type named by Go field names or hash field names.
+func (s *modelSchema) lookupFields(modelType reflect.Type, names []string) ([]reflect.StructField, error) {
+	fields := make([]reflect.StructField, len(names))
+	for i, name := range names {
+		f, ok := lookupField(modelType, name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %q", modelType.Name(), name)
+		}
+		if s.version != nil && sameIndex(f.Index, s.version.index) {
+			return nil, fmt.Errorf("%s.%s is a version field, which cannot be updated directly", modelType.Name(), name)
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// lookupHashFields returns names of hash fields storing fields named by Go field names or hash field names.
+// Nested structs are stored in multiple hash fields, and their fields can also be named with path prefixes (e.g. `author.name`).
+func lookupHashFields(modelType reflect.Type, names []string) ([]string, error) {
+	mapping := getMapping(modelType)
+	hashFields := make([]string, 0, len(names))
+	for _, name := range names {
+		if f, ok := lookupField(modelType, name); ok {
+			if fm := mapping.lookup(f.Index); fm != nil {
+				hashFields = append(hashFields, fm.names()...)
+				continue
+			}
+		}
+		if mapping.hasName(name) {
+			hashFields = append(hashFields, name)
+			continue
+		}
+		return nil, fmt.Errorf("%s has no field %q", modelType.Name(), name)
+	}
+	return hashFields, nil
+}
+
+func lookupField(modelType reflect.Type, name string) (reflect.StructField, bool) {
+	if f, ok := modelType.FieldByName(name); ok {
+		return f, true
+	}
+	if modelType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		f := modelType.Field(i)
+		if f.PkgPath == "" && f.Tag.Get("redis") != "-" && hashFieldName(f) == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// scoresDependingOn returns score fields whose names or values refer to any of the fields.
+func (s *modelSchema) scoresDependingOn(fields []reflect.StructField) []*scoreField {
+	var scores []*scoreField
+	for _, sf := range s.scores {
+	Deps:
+		for _, dep := range sf.dependencies() {
+			for _, f := range fields {
+				if sameIndex(dep, f.Index) {
+					scores = append(scores, sf)
+					break Deps
+				}
+			}
+		}
+	}
+	return scores
+}
+
+func (f *scoreField) dependencies() [][]int {
+	return append([][]int{f.value}, f.name.fields...)
+}
+
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasKey reports whether models of the schema can be stored without implementing Model.
+func (s *modelSchema) hasKey() bool {
+	return s.key != nil
+}
+
+func (s *modelSchema) getKeySuffix(m interface{}) string {
+	return fmt.Sprint(reflect.Indirect(reflect.ValueOf(m)).FieldByIndex(s.key.index).Interface())
+}
+
+func (s *modelSchema) getScoreMap(m interface{}) map[string]interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(m))
+	scoreMap := make(map[string]interface{}, len(s.scores))
+	for _, f := range s.scores {
+		scoreMap[f.name.execute(rv)] = rv.FieldByIndex(f.value).Interface()
+	}
+	return scoreMap
+}
+
+func (t *nameTemplate) execute(rv reflect.Value) string {
+	var b strings.Builder
+	for i, l := range t.literals {
+		b.WriteString(l)
+		if i < len(t.fields) {
+			fmt.Fprint(&b, rv.FieldByIndex(t.fields[i]).Interface())
+		}
+	}
+	return b.String()
+}
+
+func (f *versionField) get(m interface{}) int64 {
+	rv := reflect.Indirect(reflect.ValueOf(m)).FieldByIndex(f.index)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	default:
+		return rv.Int()
+	}
+}
+
+func (f *versionField) set(m interface{}, v int64) {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Ptr {
+		return
+	}
+	rv = rv.Elem().FieldByIndex(f.index)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		rv.SetUint(uint64(v))
+	default:
+		rv.SetInt(v)
+	}
+}
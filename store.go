@@ -12,33 +12,54 @@ import (
 // Store is an interface for providing CRUD operations for objects
 type Store interface {
 	List(ctx context.Context, dest interface{}, mods ...rq.Modifier) error
-	Get(ctx context.Context, dests ...Model) error
+	ListPage(ctx context.Context, dest interface{}, mods ...rq.Modifier) (next string, err error)
+	Get(ctx context.Context, dests ...interface{}) error
+	GetFields(ctx context.Context, fields []string, dests ...interface{}) error
 	Put(ctx context.Context, src interface{}) error
+	Update(ctx context.Context, src interface{}, fields ...string) error
 	Delete(ctx context.Context, src interface{}) error
 	DeleteAll(ctx context.Context, mods ...rq.Modifier) error
 	Count(ctx context.Context, mods ...rq.Modifier) (int, error)
+	Sweep(ctx context.Context) (int, error)
+	Reindex(ctx context.Context, opts ReindexOptions) (int, error)
+	Verify(ctx context.Context, opts VerifyOptions) (*VerifyReport, error)
+	Watch(ctx context.Context, fromID string) (<-chan *ChangeEvent[interface{}], error)
 }
 
-// Pool is a pool of redis connections.
+// Pool is a pool of redis connections, which is implemented by *redis.Pool of redigo.
+// An adapter for go-redis is provided by ro/goredis.
 type Pool interface {
-	GetContext(context.Context) (redis.Conn, error)
+	GetContext(context.Context) (Conn, error)
 }
 
+// Conn is a connection to redis, which is redis.Conn of redigo.
+// Send queues a command, Flush writes queued commands, and Receive reads a reply of them in order.
+// Do flushes queued commands and returns a reply of the command after receiving all pending replies.
+// Err returns a non-nil value when the connection is not usable.
+//
+// Replies should be compatible with reply helpers of redigo (e.g. redis.Strings):
+// bulk strings are []byte or string, integers are int64, arrays are []interface{}, nil bulk strings are nil,
+// and error replies in arrays are error values.
+type Conn = redis.Conn
+
 type redisStore struct {
 	*Config
 	pool      Pool
-	model     Model
 	modelType reflect.Type
 }
 
-// New creates a redisStore instance
-func New(pool Pool, model Model, opts ...Option) Store {
-	modelType := reflect.ValueOf(model).Elem().Type()
+// New creates a redisStore instance.
+// The model should implement Model, or have fields tagged with `ro:"key"` and `ro:"score=<name>"`.
+func New(pool Pool, model interface{}, opts ...Option) Store {
+	return newRedisStore(pool, reflect.TypeOf(model), opts)
+}
+
+func newRedisStore(pool Pool, typ reflect.Type, opts []Option) *redisStore {
+	modelType := indirectType(typ)
 
 	return &redisStore{
 		Config:    createConfig(modelType, opts),
 		pool:      pool,
-		model:     model,
 		modelType: modelType,
 	}
 }
@@ -0,0 +1,57 @@
+package ro_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestRedisStore_WithRedigoPool(t *testing.T) {
+	defer teardown(t)
+
+	redisPool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(pool.URL())
+		},
+	}
+	defer redisPool.Close()
+
+	ctx := context.Background()
+	store := ro.New(redisPool, &rotesting.Post{})
+	posts := []*rotesting.Post{
+		{ID: 1, Title: "post 1", Body: "This is a post 1.", UpdatedAt: 100},
+		{ID: 2, Title: "post 2", Body: "This is a post 2.", UpdatedAt: 200},
+	}
+
+	err := store.Put(ctx, posts)
+	if err != nil {
+		t.Fatalf("Put returns an error: %v", err)
+	}
+
+	got := []*rotesting.Post{}
+	err = store.List(ctx, &got, rq.Key("recent"), rq.Reverse())
+	if err != nil {
+		t.Fatalf("List returns an error: %v", err)
+	}
+	if want := []*rotesting.Post{posts[1], posts[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List returns %v, want %v", got, want)
+	}
+
+	err = store.Delete(ctx, posts[0])
+	if err != nil {
+		t.Fatalf("Delete returns an error: %v", err)
+	}
+	cnt, err := store.Count(ctx, rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Count returns an error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count returns %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package ro
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// sweepBatchSize is the number of expired models removed by a transaction in Sweep.
+const sweepBatchSize = 100
+
+// Sweep implements the types.Store interface.
+func (s *redisStore) Sweep(ctx context.Context) (int, error) {
+	cnt, err := s.intercept(ctx, &Operation{Name: "Sweep"}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return s.doSweep(ctx)
+	})
+	n, _ := cnt.(int)
+	return n, err
+}
+
+func (s *redisStore) doSweep(ctx context.Context) (int, error) {
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	expirationsKey := s.getExpirationsKey()
+	cnt := 0
+	for {
+		now := toMilliseconds(time.Now())
+		keys, err := redis.Strings(conn.Do("ZRANGEBYSCORE", expirationsKey, "-inf", now, "LIMIT", 0, sweepBatchSize))
+		if err != nil {
+			return cnt, errors.Wrapf(err, "failed to execute ZRANGEBYSCORE %s -inf %d", expirationsKey, now)
+		}
+		n, err := s.deleteKeys(conn, keys, true)
+		if err != nil {
+			return cnt, errors.Wrap(err, "failed to remove expired models")
+		}
+		cnt += n
+		if len(keys) < sweepBatchSize {
+			return cnt, nil
+		}
+	}
+}
+
+// rejectExpiredKeys filters out keys of models which have been expired but not swept yet.
+func (s *redisStore) rejectExpiredKeys(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return keys, nil
+	}
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	expired, err := s.getExpired(conn, keys, time.Now())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	alive := make([]string, 0, len(keys))
+	for i, k := range keys {
+		if !expired[i] {
+			alive = append(alive, k)
+		}
+	}
+	return alive, nil
+}
+
+// selectExpiredKeys returns keys of models which have been expired at now.
+func (s *redisStore) selectExpiredKeys(conn Conn, keys []string, now time.Time) ([]string, error) {
+	expired, err := s.getExpired(conn, keys, now)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	selected := make([]string, 0, len(keys))
+	for i, k := range keys {
+		if expired[i] {
+			selected = append(selected, k)
+		}
+	}
+	return selected, nil
+}
+
+// getExpired reports whether each model with keys has been expired at now, by its expiration time recorded in the expirations set.
+func (s *redisStore) getExpired(conn Conn, keys []string, now time.Time) ([]bool, error) {
+	expirationsKey := s.getExpirationsKey()
+	for _, k := range keys {
+		err := conn.Send("ZSCORE", expirationsKey, k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to send ZSCORE %s %s", expirationsKey, k)
+		}
+	}
+
+	err := conn.Flush()
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to flush ZSCORE commands")
+	}
+
+	at := float64(toMilliseconds(now))
+	expired := make([]bool, len(keys))
+	for i := range keys {
+		expireAt, err := redis.Float64(conn.Receive())
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "faild to receive or cast redis command result")
+		}
+		expired[i] = expireAt <= at
+	}
+	return expired, nil
+}
@@ -0,0 +1,236 @@
+package ro_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+type ExpiringPost struct {
+	ID        uint64        `redis:"id"`
+	Title     string        `redis:"title"`
+	UpdatedAt int64         `redis:"updated_at"`
+	TTL       time.Duration `redis:"-"`
+}
+
+func (p *ExpiringPost) GetKeySuffix() string {
+	return fmt.Sprint(p.ID)
+}
+
+func (p *ExpiringPost) GetScoreMap() map[string]interface{} {
+	return map[string]interface{}{
+		"recent": p.UpdatedAt,
+	}
+}
+
+func (p *ExpiringPost) GetTTL() time.Duration {
+	return p.TTL
+}
+
+func TestRedisStore_Put_WithTTL(t *testing.T) {
+	defer teardown(t)
+	post := &rotesting.Post{
+		ID:        1,
+		Title:     "post 1",
+		Body:      "This is a post 1.",
+		UpdatedAt: time.Now().UTC().UnixNano(),
+	}
+
+	store := ro.New(pool, &rotesting.Post{}, ro.WithTTL(time.Hour), ro.WithExpirationGracePeriod(time.Hour))
+	err := store.Put(context.TODO(), post)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	ttl, err := redis.Int64(conn.Do("PTTL", "Post:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if ttl <= 0 || ttl > int64(time.Hour/time.Millisecond) {
+		t.Errorf("TTL of Post:1 is %dms, want (0, 1h]", ttl)
+	}
+
+	ttl, err = redis.Int64(conn.Do("PTTL", "Post:1:scoreSetKeys"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if ttl <= int64(time.Hour/time.Millisecond) {
+		t.Errorf("TTL of Post:1:scoreSetKeys is %dms, want longer than 1h", ttl)
+	}
+
+	keys, err := redis.Strings(conn.Do("ZRANGE", "Post#expirations", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := keys, []string{"Post:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Post#expirations contains %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Sweep(t *testing.T) {
+	defer teardown(t)
+	now := time.Now().UTC()
+	posts := []*ExpiringPost{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano(), TTL: time.Millisecond},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * time.Second).UnixNano(), TTL: time.Hour},
+		{ID: 3, Title: "post 3", UpdatedAt: now.Add(1 * time.Second).UnixNano()},
+	}
+
+	store := ro.New(pool, &ExpiringPost{})
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	gotPosts := []*ExpiringPost{}
+	err = store.List(context.TODO(), &gotPosts, rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := len(gotPosts), 2; got != want {
+		t.Errorf("List() returned %d posts, want %d posts", got, want)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 2; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+
+	swept, err := store.Sweep(context.TODO())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := swept, 1; got != want {
+		t.Errorf("Sweep() returned %d, want %d", got, want)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("ZRANGE", "ExpiringPost/recent", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := keys, []string{"ExpiringPost:2", "ExpiringPost:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpiringPost/recent contains %v, want %v", got, want)
+	}
+
+	keys, err = redis.Strings(conn.Do("ZRANGE", "ExpiringPost#expirations", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := keys, []string{"ExpiringPost:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpiringPost#expirations contains %v, want %v", got, want)
+	}
+
+	for _, k := range []string{"ExpiringPost:1", "ExpiringPost:1:scoreSetKeys"} {
+		n, err := redis.Int(conn.Do("EXISTS", k))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if n != 0 {
+			t.Errorf("%s should be removed", k)
+		}
+	}
+}
+
+type Tag struct {
+	Name string `redis:"name" ro:"key"`
+	Rank int64  `redis:"rank" ro:"score=rank"`
+}
+
+func TestRedisStore_Put_WithExpirationsKeySuffix(t *testing.T) {
+	defer teardown(t)
+	store := ro.New(pool, &Tag{}, ro.WithTTL(time.Hour))
+
+	err := store.Put(context.TODO(), []*Tag{{Name: "go", Rank: 1}, {Name: "expirations", Rank: 2}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotTags := []*Tag{}
+	err = store.List(context.TODO(), &gotTags, rq.Key("rank"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := len(gotTags), 2; got != want {
+		t.Errorf("List() returned %d tags, want %d tags", got, want)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("ZRANGE", "Tag#expirations", 0, -1))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := keys, []string{"Tag:expirations", "Tag:go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tag#expirations contains %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Count_WithExpiredModels(t *testing.T) {
+	defer teardown(t)
+	now := time.Now().UTC()
+	posts := []*ExpiringPost{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano(), TTL: time.Millisecond},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * time.Second).UnixNano(), TTL: time.Hour},
+		{ID: 3, Title: "post 3", UpdatedAt: now.Add(1 * time.Second).UnixNano()},
+		{ID: 4, Title: "post 4", UpdatedAt: now.Add(2 * time.Second).UnixNano(), TTL: time.Millisecond},
+	}
+
+	store := ro.New(pool, &ExpiringPost{})
+	err := store.Put(context.TODO(), posts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	cases := []struct {
+		mods []rq.Modifier
+		cnt  int
+	}{
+		{mods: []rq.Modifier{rq.Key("recent")}, cnt: 2},
+		{mods: []rq.Modifier{rq.Key("recent"), rq.GtEq(now.UnixNano())}, cnt: 1},
+		{mods: []rq.Modifier{rq.Key("recent"), rq.Lt(now.UnixNano())}, cnt: 1},
+		{mods: []rq.Modifier{rq.Union(rq.SetKey{"recent"}), rq.GtEq(now.UnixNano())}, cnt: 1},
+	}
+
+	for _, c := range cases {
+		cnt, err := store.Count(context.TODO(), c.mods...)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got, want := cnt, c.cnt; got != want {
+			t.Errorf("Count() returned %d, want %d", got, want)
+		}
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "ExpiringPost#tmp*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Count() left temporary keys %v", keys)
+	}
+}
@@ -4,19 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
+	"os/exec"
+	"strconv"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gomodule/redigo/redis"
 	dockertest "gopkg.in/ory-am/dockertest.v3"
+
+	"github.com/izumin5210/ro"
 )
 
-// Pool contains dockertest and redis connection pool
+// Pool contains a redis server for tests and redis connection pool
 type Pool struct {
 	redisPool  *redis.Pool
+	url        string
 	dockerPool *dockertest.Pool
 	dockerRes  *dockertest.Resource
+	miniRedis  *miniredis.Miniredis
+	serverCmd  *exec.Cmd
 }
 
-// MustCreate creates new pool object
+// MustCreate creates new pool object with a redis container started by dockertest
 func MustCreate() *Pool {
 	p := &Pool{}
 
@@ -31,23 +41,83 @@ func MustCreate() *Pool {
 		log.Fatalf("could not start resource: %s", err)
 	}
 
+	p.connect(fmt.Sprintf("redis://localhost:%s", p.dockerRes.GetPort("6379/tcp")))
+
+	if err = p.dockerPool.Retry(p.ping); err != nil {
+		log.Fatalf("could not connect to docker: %s", err)
+	}
+
+	return p
+}
+
+// MustCreateLocal creates new pool object without docker.
+// It launches redis-server when the binary is found in PATH, or serves the redis protocol in process with miniredis.
+func MustCreateLocal() *Pool {
+	p := &Pool{}
+
+	if path, err := exec.LookPath("redis-server"); err == nil {
+		port, err := getFreePort()
+		if err != nil {
+			log.Fatalf("could not find a free port: %s", err)
+		}
+		p.serverCmd = exec.Command(path, "--port", strconv.Itoa(port), "--bind", "127.0.0.1", "--save", "", "--appendonly", "no")
+		if err = p.serverCmd.Start(); err != nil {
+			log.Fatalf("could not start redis-server: %s", err)
+		}
+		p.connect(fmt.Sprintf("redis://127.0.0.1:%d", port))
+	} else {
+		p.miniRedis, err = miniredis.Run()
+		if err != nil {
+			log.Fatalf("could not start miniredis: %s", err)
+		}
+		p.connect(fmt.Sprintf("redis://%s", p.miniRedis.Addr()))
+	}
+
+	if err := retry(p.ping); err != nil {
+		if p.serverCmd != nil {
+			p.serverCmd.Process.Kill()
+		}
+		log.Fatalf("could not connect to redis: %s", err)
+	}
+
+	return p
+}
+
+func (p *Pool) connect(url string) {
+	p.url = url
 	p.redisPool = &redis.Pool{
 		Dial: func() (redis.Conn, error) {
-			return redis.DialURL(fmt.Sprintf("redis://localhost:%s", p.dockerRes.GetPort("6379/tcp")))
+			return redis.DialURL(p.url)
 		},
 	}
+}
 
-	if err = p.dockerPool.Retry(func() error {
-		conn := p.Get()
-		defer conn.Close()
-		_, err := conn.Do("PING")
+func (p *Pool) ping() error {
+	conn := p.Get()
+	defer conn.Close()
+	_, err := conn.Do("PING")
 
-		return err
-	}); err != nil {
-		log.Fatalf("could not connect to docker: %s", err)
+	return err
+}
+
+func getFreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
 	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
 
-	return p
+func retry(f func() error) error {
+	var err error
+	for wait := 10 * time.Millisecond; wait < 5*time.Second; wait *= 2 {
+		if err = f(); err == nil {
+			return nil
+		}
+		time.Sleep(wait)
+	}
+	return err
 }
 
 // Get gets a connection with redis
@@ -55,11 +125,16 @@ func (p *Pool) Get() redis.Conn {
 	return p.redisPool.Get()
 }
 
-// GetContext gets a connection with redis
-func (p *Pool) GetContext(ctx context.Context) (redis.Conn, error) {
+// GetContext gets a connection with redis, which implements the ro.Pool interface
+func (p *Pool) GetContext(ctx context.Context) (ro.Conn, error) {
 	return p.redisPool.GetContext(ctx)
 }
 
+// URL returns a url of redis
+func (p *Pool) URL() string {
+	return p.url
+}
+
 // Cleanup remove all data in redis
 func (p *Pool) Cleanup() error {
 	conn := p.Get()
@@ -68,7 +143,7 @@ func (p *Pool) Cleanup() error {
 	return err
 }
 
-// MustClose closes redis connection pool and dockertest pool
+// MustClose closes redis connection pool and stops the redis server
 func (p *Pool) MustClose() {
 	var errs []error
 	if err := p.Cleanup(); err != nil {
@@ -77,8 +152,19 @@ func (p *Pool) MustClose() {
 	if err := p.redisPool.Close(); err != nil {
 		errs = append(errs, err)
 	}
-	if err := p.dockerPool.Purge(p.dockerRes); err != nil {
-		errs = append(errs, err)
+	if p.dockerPool != nil {
+		if err := p.dockerPool.Purge(p.dockerRes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.miniRedis != nil {
+		p.miniRedis.Close()
+	}
+	if p.serverCmd != nil {
+		if err := p.serverCmd.Process.Kill(); err != nil {
+			errs = append(errs, err)
+		}
+		p.serverCmd.Wait()
 	}
 	if len(errs) > 0 {
 		log.Fatalf("unexpected error: %v", errs[0])
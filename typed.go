@@ -0,0 +1,151 @@
+package ro
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/izumin5210/ro/rq"
+)
+
+// TypedStore is a type-safe variant of Store.
+// T can be either a pointer to a model struct (e.g. *Post) or a model struct itself (e.g. Post).
+// Get and GetFields return found models with a NotFoundError when some of models are missing.
+type TypedStore[T any] interface {
+	List(ctx context.Context, mods ...rq.Modifier) ([]T, error)
+	ListPage(ctx context.Context, mods ...rq.Modifier) (models []T, next string, err error)
+	Get(ctx context.Context, suffixes ...string) ([]T, error)
+	GetFields(ctx context.Context, fields []string, suffixes ...string) ([]T, error)
+	Put(ctx context.Context, srcs ...T) error
+	Update(ctx context.Context, src T, fields ...string) error
+	Delete(ctx context.Context, srcs ...T) error
+	DeleteAll(ctx context.Context, mods ...rq.Modifier) error
+	Count(ctx context.Context, mods ...rq.Modifier) (int, error)
+	Sweep(ctx context.Context) (int, error)
+	Reindex(ctx context.Context, opts ReindexOptions) (int, error)
+	Verify(ctx context.Context, opts VerifyOptions) (*VerifyReport, error)
+	Watch(ctx context.Context, fromID string) (<-chan *ChangeEvent[T], error)
+}
+
+type typedStore[T any] struct {
+	store *redisStore
+	isPtr bool
+}
+
+// NewTyped creates a TypedStore instance for T.
+func NewTyped[T any](pool Pool, opts ...Option) TypedStore[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	return &typedStore[T]{
+		store: newRedisStore(pool, typ, opts),
+		isPtr: typ.Kind() == reflect.Ptr,
+	}
+}
+
+// List implements the TypedStore interface.
+func (s *typedStore[T]) List(ctx context.Context, mods ...rq.Modifier) ([]T, error) {
+	dest := []T{}
+	err := s.store.List(ctx, &dest, mods...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dest, nil
+}
+
+// ListPage implements the TypedStore interface.
+func (s *typedStore[T]) ListPage(ctx context.Context, mods ...rq.Modifier) ([]T, string, error) {
+	dest := []T{}
+	next, err := s.store.ListPage(ctx, &dest, mods...)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	return dest, next, nil
+}
+
+// Get implements the TypedStore interface.
+func (s *typedStore[T]) Get(ctx context.Context, suffixes ...string) ([]T, error) {
+	return s.GetFields(ctx, nil, suffixes...)
+}
+
+// GetFields implements the TypedStore interface.
+func (s *typedStore[T]) GetFields(ctx context.Context, fields []string, suffixes ...string) ([]T, error) {
+	dests := make([]interface{}, len(suffixes))
+	for i := range suffixes {
+		dests[i] = reflect.New(s.store.modelType).Interface()
+	}
+
+	found, err := s.store.getFields(ctx, suffixes, fields, dests)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, errors.WithStack(err)
+	}
+
+	models := make([]T, 0, len(dests))
+	for i, d := range dests {
+		if i < len(found) && found[i] {
+			models = append(models, s.fromPtr(reflect.ValueOf(d)))
+		}
+	}
+	return models, err
+}
+
+// Put implements the TypedStore interface.
+func (s *typedStore[T]) Put(ctx context.Context, srcs ...T) error {
+	return s.store.Put(ctx, srcs)
+}
+
+func (s *typedStore[T]) putWithTTL(ctx context.Context, ttl time.Duration, srcs ...T) error {
+	return s.store.putWithTTL(ctx, srcs, ttl)
+}
+
+func (s *typedStore[T]) isExpirable() bool {
+	return s.store.isExpirable()
+}
+
+// Update implements the TypedStore interface.
+func (s *typedStore[T]) Update(ctx context.Context, src T, fields ...string) error {
+	return s.store.Update(ctx, src, fields...)
+}
+
+// Delete implements the TypedStore interface.
+func (s *typedStore[T]) Delete(ctx context.Context, srcs ...T) error {
+	return s.store.Delete(ctx, srcs)
+}
+
+// DeleteAll implements the TypedStore interface.
+func (s *typedStore[T]) DeleteAll(ctx context.Context, mods ...rq.Modifier) error {
+	return s.store.DeleteAll(ctx, mods...)
+}
+
+// Count implements the TypedStore interface.
+func (s *typedStore[T]) Count(ctx context.Context, mods ...rq.Modifier) (int, error) {
+	return s.store.Count(ctx, mods...)
+}
+
+func (s *typedStore[T]) fromPtr(rv reflect.Value) T {
+	if s.isPtr {
+		return rv.Interface().(T)
+	}
+	return rv.Elem().Interface().(T)
+}
+
+// Sweep implements the TypedStore interface.
+func (s *typedStore[T]) Sweep(ctx context.Context) (int, error) {
+	return s.store.Sweep(ctx)
+}
+
+// Reindex implements the TypedStore interface.
+func (s *typedStore[T]) Reindex(ctx context.Context, opts ReindexOptions) (int, error) {
+	return s.store.Reindex(ctx, opts)
+}
+
+// Verify implements the TypedStore interface.
+func (s *typedStore[T]) Verify(ctx context.Context, opts VerifyOptions) (*VerifyReport, error) {
+	return s.store.Verify(ctx, opts)
+}
+
+// Watch implements the TypedStore interface.
+func (s *typedStore[T]) Watch(ctx context.Context, fromID string) (<-chan *ChangeEvent[T], error) {
+	return watchChanges(ctx, s.store, fromID, true, s.fromPtr)
+}
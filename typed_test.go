@@ -0,0 +1,195 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+type ValuePost struct {
+	ID        uint64 `redis:"id"`
+	Title     string `redis:"title"`
+	UpdatedAt int64  `redis:"updated_at"`
+}
+
+func (p ValuePost) GetKeySuffix() string {
+	return fmt.Sprint(p.ID)
+}
+
+func (p ValuePost) GetScoreMap() map[string]interface{} {
+	return map[string]interface{}{
+		"recent": p.UpdatedAt,
+	}
+}
+
+func TestTypedStore(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+
+	now := time.Now().UTC()
+	posts := []*rotesting.Post{
+		{
+			ID:        1,
+			Title:     "post 1",
+			Body:      "This is a post 1.",
+			UpdatedAt: now.UnixNano(),
+		},
+		{
+			ID:        2,
+			Title:     "post 2",
+			Body:      "This is a post 2.",
+			UpdatedAt: now.Add(-1 * 60 * 60 * time.Second).UnixNano(),
+		},
+		{
+			ID:        3,
+			Title:     "post 3",
+			Body:      "This is a post 3.",
+			UpdatedAt: now.Add(1 * 60 * 60 * time.Second).UnixNano(),
+		},
+	}
+
+	err := store.Put(context.TODO(), posts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts, err := store.List(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{posts[1], posts[0], posts[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	gotPosts, err = store.Get(context.TODO(), "3", "1")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{posts[2], posts[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+
+	err = store.Delete(context.TODO(), posts[0], posts[2])
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestTypedStore_WithValues(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[ValuePost](pool)
+
+	now := time.Now().UTC()
+	posts := []ValuePost{
+		{ID: 1, Title: "post 1", UpdatedAt: now.UnixNano()},
+		{ID: 2, Title: "post 2", UpdatedAt: now.Add(-1 * 60 * 60 * time.Second).UnixNano()},
+	}
+
+	err := store.Put(context.TODO(), posts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts, err := store.List(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []ValuePost{posts[1], posts[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	gotPosts, err = store.Get(context.TODO(), "2")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []ValuePost{posts[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+
+	err = store.DeleteAll(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 0; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestTypedStore_WithTaggedModel(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*TaggedPost](pool)
+
+	posts := []*TaggedPost{
+		{ID: 1, UserID: 1, Title: "post 1", CreatedAt: 200},
+		{ID: 2, UserID: 2, Title: "post 2", CreatedAt: 100},
+		{ID: 3, UserID: 1, Title: "post 3", CreatedAt: 300},
+	}
+
+	err := store.Put(context.TODO(), posts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts, err := store.List(context.TODO(), rq.Key("user", 1), rq.Reverse())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPosts, []*TaggedPost{posts[2], posts[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %v, want %v", got, want)
+	}
+
+	err = store.Delete(context.TODO(), posts[0])
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cnt, err := store.Count(context.TODO(), rq.Key("recent"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := cnt, 2; got != want {
+		t.Errorf("Count() returned %d, want %d", got, want)
+	}
+}
+
+func TestTypedStore_Get_WhenNotFound(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool)
+	post := &rotesting.Post{ID: 1, Title: "post 1"}
+	err := store.Put(context.TODO(), post)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gotPosts, err := store.Get(context.TODO(), "1", "2")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Get() returned %v, want ErrNotFound", err)
+	}
+	if got, want := gotPosts, []*rotesting.Post{post}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() returned %v, want %v", got, want)
+	}
+}
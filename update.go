@@ -0,0 +1,388 @@
+package ro
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// Update implements the types.Store interface.
+func (s *redisStore) Update(ctx context.Context, src interface{}, fields ...string) error {
+	_, err := s.intercept(ctx, &Operation{Name: "Update", Models: src, Fields: fields}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return nil, s.doUpdate(ctx, op.Models, op.Fields)
+	})
+	return err
+}
+
+func (s *redisStore) doUpdate(ctx context.Context, src interface{}, fields []string) error {
+	if !s.HashStoreEnabled {
+		return errors.New("Update requires the hash store")
+	}
+	if len(fields) == 0 {
+		return errors.New("fields to update are required")
+	}
+
+	m, err := s.toModel(reflect.ValueOf(src))
+	if err != nil {
+		return errors.Wrap(err, "failed to convert to model")
+	}
+
+	schema, err := getSchema(s.modelType)
+	if err != nil {
+		return errors.Wrap(err, "invalid model schema")
+	}
+
+	targets, err := schema.lookupFields(s.modelType, fields)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	key, err := s.getKey(m)
+	if err != nil {
+		return errors.Wrap(err, "failed to get key")
+	}
+	e := &putEntry{model: m, key: key}
+	if schema.version != nil {
+		e.version = schema.version.get(m)
+	}
+
+	u := &updateEntry{putEntry: e, fields: targets, scores: schema.scoresDependingOn(targets)}
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	for i := 0; i < txRetryLimit; i++ {
+		err = s.update(conn, u, schema.version)
+		if errors.Cause(err) != errTxAborted {
+			break
+		}
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.invalidateNearCache(e.key)
+
+	if schema.version != nil {
+		schema.version.set(m, e.version+1)
+	}
+
+	return nil
+}
+
+// updateEntry contains fields to update and scores depending on them.
+// When the model implements Model, dependencies of scores are unknown, so all of them are re-indexed.
+type updateEntry struct {
+	*putEntry
+	fields []reflect.StructField
+	scores []*scoreField
+	ttl    int64
+}
+
+// update writes fields of the entry in a transaction.
+// Stored values of fields which affected scores refer to are read in the transaction,
+// so score set memberships produced by the stored values are replaced with new ones.
+func (s *redisStore) update(conn Conn, u *updateEntry, version *versionField) error {
+	key := u.key
+	scoreSetKeysKey := s.getScoreSetKeysKeyByKey(key)
+	lexSetMembersKey := s.getLexSetMembersKeyByKey(key)
+	_, explicit := u.model.(Model)
+
+	watchedKeys := redis.Args{}.Add(key, scoreSetKeysKey)
+	if explicit && s.isLexIndexed() {
+		watchedKeys = watchedKeys.Add(lexSetMembersKey)
+	}
+
+	_, err := conn.Do("WATCH", watchedKeys...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to execute WATCH %v", watchedKeys)
+	}
+
+	e, currentZsetKeys, currentLexMembers, err := s.prepareUpdate(conn, u, explicit)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return errors.WithStack(err)
+	}
+
+	if version != nil {
+		err = s.checkVersions(conn, []*putEntry{e}, version)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return errors.WithStack(err)
+		}
+	}
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		conn.Do("UNWATCH")
+		return errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	err = s.sendUpdate(conn, u, e, currentZsetKeys, currentLexMembers, explicit)
+	if err == nil && version != nil && s.Codec == nil {
+		err = conn.Send("HSET", key, version.hashField, e.version+1)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to send HSET %s %s %d", key, version.hashField, e.version+1)
+		}
+	}
+	if err == nil && s.ChangeFeedEnabled {
+		err = s.sendEntryChange(conn, ChangeUpdate, e, u.fields, currentZsetKeys, version)
+	}
+	if err != nil {
+		conn.Do("DISCARD")
+		return errors.Wrap(err, "faild to send any commands")
+	}
+
+	reply, err := conn.Do("EXEC")
+	if err != nil {
+		return errors.Wrap(err, "faild to EXEC commands")
+	}
+	if reply == nil {
+		return errTxAborted
+	}
+	return nil
+}
+
+// prepareUpdate reads current states of the model, and returns an entry containing scores to set with current memberships.
+// With a codec or a model implementing Model, a model of the returned entry is the stored model merged with the fields.
+func (s *redisStore) prepareUpdate(conn Conn, u *updateEntry, explicit bool) (*putEntry, []string, map[string]string, error) {
+	key := u.key
+
+	stored, exists, err := s.readStoredModel(conn, u, explicit)
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+	if !exists {
+		suffix, err := getKeySuffix(u.model)
+		if err != nil {
+			return nil, nil, nil, errors.WithStack(err)
+		}
+		return nil, nil, nil, &NotFoundError{Suffixes: []string{suffix}}
+	}
+
+	var updated reflect.Value
+	if s.Codec != nil || explicit {
+		updated = u.merge(stored)
+	}
+
+	if explicit {
+		scoreSetKeysKey := s.getScoreSetKeysKeyByKey(key)
+		zsetKeysByKey, err := s.getScoreSetKeysByKeys(conn, []string{scoreSetKeysKey})
+		if err != nil {
+			return nil, nil, nil, errors.WithStack(err)
+		}
+		var lexMembers map[string]string
+		if s.isLexIndexed() {
+			lexSetMembersKey := s.getLexSetMembersKeyByKey(key)
+			lexMembersByKey, err := s.getLexMembersByKeys(conn, []string{lexSetMembersKey})
+			if err != nil {
+				return nil, nil, nil, errors.WithStack(err)
+			}
+			lexMembers = lexMembersByKey[lexSetMembersKey]
+		}
+		// scores are computed from the merged model since the given model may be partial
+		e, err := s.createPutEntry(updated.Addr().Interface(), time.Now())
+		if err != nil {
+			return nil, nil, nil, errors.WithStack(err)
+		}
+		e.version = u.version
+		return e, zsetKeysByKey[scoreSetKeysKey], lexMembers, nil
+	}
+
+	e := &putEntry{model: u.model, key: key, version: u.version, scores: map[string]interface{}{}}
+	if s.Codec != nil {
+		e.model = updated.Addr().Interface()
+	}
+	if len(u.scores) == 0 {
+		return e, nil, nil, nil
+	}
+
+	if s.Codec == nil {
+		stored, err = s.getStoredFields(conn, key, u.scores)
+		if err != nil {
+			return nil, nil, nil, errors.WithStack(err)
+		}
+		updated = u.merge(stored)
+	}
+
+	currentZsetKeys := make([]string, 0, len(u.scores))
+	for _, sf := range u.scores {
+		currentZsetKeys = append(currentZsetKeys, s.getScoreSetKey(sf.name.execute(stored)))
+		e.scores[s.getScoreSetKey(sf.name.execute(updated))] = updated.FieldByIndex(sf.value).Interface()
+	}
+
+	return e, currentZsetKeys, nil, nil
+}
+
+// readStoredModel reports whether the model is stored, and decodes it and reads its TTL with a codec.
+// Unless all scores are re-indexed, hashes are not read here since only fields affected scores refer to are needed.
+func (s *redisStore) readStoredModel(conn Conn, u *updateEntry, explicit bool) (reflect.Value, bool, error) {
+	key := u.key
+
+	if s.Codec == nil && explicit {
+		pairs, found, err := readHashes(conn, []string{key}, nil)
+		if err != nil {
+			return reflect.Value{}, false, errors.WithStack(err)
+		}
+		if !found[0] {
+			return reflect.Value{}, false, nil
+		}
+		stored := reflect.New(s.modelType)
+		err = scanHash(pairs[0], stored.Interface())
+		if err != nil {
+			return reflect.Value{}, false, errors.Wrapf(err, "faild to scan struct %s", key)
+		}
+		return stored.Elem(), true, nil
+	}
+
+	if s.Codec == nil {
+		exists, err := redis.Bool(conn.Do("EXISTS", key))
+		if err != nil {
+			return reflect.Value{}, false, errors.Wrapf(err, "failed to execute EXISTS %s", key)
+		}
+		return reflect.Value{}, exists, nil
+	}
+
+	err := conn.Send("GET", key)
+	if err != nil {
+		return reflect.Value{}, false, errors.Wrapf(err, "failed to send GET %s", key)
+	}
+	err = conn.Send("PTTL", key)
+	if err != nil {
+		return reflect.Value{}, false, errors.Wrapf(err, "failed to send PTTL %s", key)
+	}
+	err = conn.Flush()
+	if err != nil {
+		return reflect.Value{}, false, errors.Wrap(err, "faild to flush GET and PTTL commands")
+	}
+	data, err := redis.Bytes(conn.Receive())
+	if err != nil && err != redis.ErrNil {
+		return reflect.Value{}, false, errors.Wrapf(err, "failed to execute GET %s", key)
+	}
+	u.ttl, err = redis.Int64(conn.Receive())
+	if err != nil {
+		return reflect.Value{}, false, errors.Wrapf(err, "failed to execute PTTL %s", key)
+	}
+	if data == nil {
+		return reflect.Value{}, false, nil
+	}
+	stored, err := s.decode(data)
+	if err != nil {
+		return reflect.Value{}, false, errors.Wrapf(err, "failed to decode %s %q", key, data)
+	}
+	return stored, true, nil
+}
+
+// merge returns a copy of the stored model overwritten with the fields of the entry.
+func (u *updateEntry) merge(stored reflect.Value) reflect.Value {
+	updated := reflect.New(stored.Type()).Elem()
+	updated.Set(stored)
+	rv := reflect.Indirect(reflect.ValueOf(u.model))
+	for _, f := range u.fields {
+		updated.FieldByIndex(f.Index).Set(rv.FieldByIndex(f.Index))
+	}
+	return updated
+}
+
+// getStoredFields reads fields which the score fields refer to from the hash.
+func (s *redisStore) getStoredFields(conn Conn, key string, scores []*scoreField) (reflect.Value, error) {
+	mapping := getMapping(s.modelType)
+	var hashFields []string
+	seen := map[string]struct{}{}
+	for _, sf := range scores {
+		for _, idx := range sf.dependencies() {
+			fm := mapping.lookup(idx)
+			if fm == nil {
+				return reflect.Value{}, errors.Errorf("%s.%v is not stored", s.modelType.Name(), idx)
+			}
+			for _, name := range fm.names() {
+				if _, ok := seen[name]; ok {
+					continue
+				}
+				seen[name] = struct{}{}
+				hashFields = append(hashFields, name)
+			}
+		}
+	}
+
+	values, err := redis.Values(conn.Do("HMGET", redis.Args{}.Add(key).AddFlat(hashFields)...))
+	if err != nil {
+		return reflect.Value{}, errors.Wrapf(err, "failed to execute HMGET %s %v", key, hashFields)
+	}
+
+	pairs := make([]interface{}, 0, 2*len(values))
+	for i, v := range values {
+		if v != nil {
+			pairs = append(pairs, []byte(hashFields[i]), v)
+		}
+	}
+
+	stored := reflect.New(s.modelType)
+	err = scanHash(pairs, stored.Interface())
+	if err != nil {
+		return reflect.Value{}, errors.Wrapf(err, "faild to scan struct %s %x", key, pairs)
+	}
+	return stored.Elem(), nil
+}
+
+func (s *redisStore) sendUpdate(conn Conn, u *updateEntry, e *putEntry, currentZsetKeys []string, currentLexMembers map[string]string, explicit bool) error {
+	err := s.sendUpdateFields(conn, u, e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = s.setScores(conn, e, currentZsetKeys)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if explicit {
+		err = s.setLexMembers(conn, e, currentLexMembers)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// sendUpdateFields writes the fields into the hash, or writes the merged model with a codec.
+// SET clears a TTL of the key, so the TTL read in the transaction is restored.
+func (s *redisStore) sendUpdateFields(conn Conn, u *updateEntry, e *putEntry) error {
+	key := e.key
+
+	if s.Codec == nil {
+		var pairs []interface{}
+		var nulls []string
+		for _, f := range u.fields {
+			p, n, err := toHashFields(u.model, f.Index)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			pairs, nulls = append(pairs, p...), append(nulls, n...)
+		}
+		return errors.WithStack(sendHash(conn, key, pairs, nulls))
+	}
+
+	data, err := s.encode(e.model, e.version+1)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = conn.Send("SET", key, data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send SET %s %q", key, data)
+	}
+	if u.ttl > 0 {
+		err = conn.Send("PEXPIRE", key, u.ttl)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send PEXPIRE %s %d", key, u.ttl)
+		}
+	}
+	return nil
+}
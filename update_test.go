@@ -0,0 +1,224 @@
+package ro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+)
+
+func TestRedisStore_Update(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &TaggedPost{})
+	err := store.Put(context.TODO(), &TaggedPost{ID: 1, UserID: 1, Title: "post 1", CreatedAt: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = store.Update(context.TODO(), &TaggedPost{ID: 1, CreatedAt: 200}, "CreatedAt")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = store.Update(context.TODO(), &TaggedPost{ID: 1, UserID: 2}, "UserID")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	gotPost := &TaggedPost{ID: 1}
+	err = store.Get(context.TODO(), gotPost)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPost, (&TaggedPost{ID: 1, UserID: 2, Title: "post 1", CreatedAt: 200}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored post is %v, want %v", got, want)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "TaggedPost*"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if got, want := keys, []string{"TaggedPost/id", "TaggedPost/recent", "TaggedPost/user:2", "TaggedPost:1", "TaggedPost:1:scoreSetKeys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored keys are %v, want %v", got, want)
+	}
+
+	for k, want := range map[string]int64{"TaggedPost/id": 1, "TaggedPost/recent": 200, "TaggedPost/user:2": 200} {
+		got, err := redis.Int64(conn.Do("ZSCORE", k, "TaggedPost:1"))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("Score in %s is %d, want %d", k, got, want)
+		}
+	}
+
+	zsetKeys, err := redis.Strings(conn.Do("SMEMBERS", "TaggedPost:1:scoreSetKeys"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(zsetKeys)
+	if got, want := zsetKeys, []string{"TaggedPost/id", "TaggedPost/recent", "TaggedPost/user:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Recorded score set keys are %v, want %v", got, want)
+	}
+}
+
+func TestRedisStore_Update_WithModel(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Post{})
+	err := store.Put(context.TODO(), &Post{ID: 1, UserID: 1, Title: "post 1", Body: "body 1", CreatedAt: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	// scores are computed from the stored model merged with the fields
+	err = store.Update(context.TODO(), &Post{ID: 1, UserID: 2, Title: "updated post 1", CreatedAt: 200}, "Title")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	gotPost := &Post{ID: 1}
+	err = store.Get(context.TODO(), gotPost)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := gotPost, (&Post{ID: 1, UserID: 1, Title: "updated post 1", Body: "body 1", CreatedAt: 100}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored post is %v, want %v", got, want)
+	}
+
+	for k, want := range map[string]int{"user:1": 1, "user:2": 0} {
+		cnt, err := store.Count(context.TODO(), rq.Key(k))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got := cnt; got != want {
+			t.Errorf("Count(%s) returned %d, want %d", k, got, want)
+		}
+	}
+
+	score, err := redis.Int64(conn.Do("ZSCORE", "Post/recent", "Post:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := score, int64(100); got != want {
+		t.Errorf("Score in Post/recent is %d, want %d", got, want)
+	}
+
+	err = store.Update(context.TODO(), &Post{ID: 1, UserID: 2}, "UserID")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for k, want := range map[string]int{"user:1": 0, "user:2": 1} {
+		cnt, err := store.Count(context.TODO(), rq.Key(k))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got := cnt; got != want {
+			t.Errorf("Count(%s) returned %d after updating UserID, want %d", k, got, want)
+		}
+	}
+
+	score, err = redis.Int64(conn.Do("ZSCORE", "Post/user:2", "Post:1"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := score, int64(100); got != want {
+		t.Errorf("Score in Post/user:2 is %d, want %d", got, want)
+	}
+}
+
+func TestRedisStore_Update_WithVersion(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &VersionedPost{})
+	post := &VersionedPost{ID: 1, Title: "post 1"}
+	err := store.Put(context.TODO(), post)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	post.Title = "updated post 1"
+	err = store.Update(context.TODO(), post, "Title")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got, want := post.Version, int64(2); got != want {
+		t.Errorf("Update() set version %d, want %d", got, want)
+	}
+
+	err = store.Update(context.TODO(), &VersionedPost{ID: 1, Title: "stale post 1", Version: 1}, "Title")
+	if !errors.Is(err, ro.ErrConflict) {
+		t.Errorf("Update() with a stale version returned %v, want ErrConflict", err)
+	}
+}
+
+func TestRedisStore_Update_WithInvalidArgs(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &TaggedPost{})
+	err := store.Put(context.TODO(), &TaggedPost{ID: 1, UserID: 1, Title: "post 1", CreatedAt: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		post   *TaggedPost
+		fields []string
+	}{
+		{
+			name: "without fields",
+			post: &TaggedPost{ID: 1},
+		},
+		{
+			name:   "with an unknown field",
+			post:   &TaggedPost{ID: 1},
+			fields: []string{"Body"},
+		},
+		{
+			name:   "when the model does not exist",
+			post:   &TaggedPost{ID: 2, Title: "post 2"},
+			fields: []string{"Title"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := store.Update(context.TODO(), c.post, c.fields...)
+			if err == nil {
+				t.Error("Update() should return an error")
+			}
+		})
+	}
+
+	err = store.Update(context.TODO(), &TaggedPost{ID: 2, Title: "post 2"}, "Title")
+	if !errors.Is(err, ro.ErrNotFound) {
+		t.Errorf("Update() for a missing model returned %v, want ErrNotFound", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	exists, err := redis.Bool(conn.Do("EXISTS", "TaggedPost:2"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if exists {
+		t.Error("Update() should not create a missing model")
+	}
+}
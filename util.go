@@ -2,8 +2,12 @@ package ro
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
@@ -11,62 +15,299 @@ import (
 	"github.com/izumin5210/ro/rq"
 )
 
-func (s *redisStore) getKey(m Model) (string, error) {
-	suffix := m.GetKeySuffix()
+func (s *redisStore) getKey(m interface{}) (string, error) {
+	suffix, err := getKeySuffix(m)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return s.getKeyBySuffix(suffix)
+}
+
+// getKeySuffix returns a key suffix of the model.
+// Models implementing Model take priority over `ro` struct tags.
+func getKeySuffix(m interface{}) (string, error) {
+	if m, ok := m.(Model); ok {
+		return m.GetKeySuffix(), nil
+	}
+	schema, err := getTaggedSchema(m)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return schema.getKeySuffix(m), nil
+}
+
+// getScoreMap returns scores of the model keyed by names of score sets.
+// Models implementing Model take priority over `ro` struct tags.
+func getScoreMap(m interface{}) (map[string]interface{}, error) {
+	if m, ok := m.(Model); ok {
+		return m.GetScoreMap(), nil
+	}
+	schema, err := getTaggedSchema(m)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return schema.getScoreMap(m), nil
+}
+
+func getTaggedSchema(m interface{}) (*modelSchema, error) {
+	schema, err := getSchema(indirectType(reflect.TypeOf(m)))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid model schema")
+	}
+	if !schema.hasKey() {
+		return nil, errors.Errorf("%T should implement ro.Model or have a field tagged with `ro:\"key\"`", m)
+	}
+	return schema, nil
+}
+
+func (s *redisStore) getKeyBySuffix(suffix string) (string, error) {
 	if len(suffix) == 0 {
 		return "", errors.New("GetKeySuffix() should be present")
 	}
-	return s.KeyPrefix + s.KeyDelimiter + suffix, nil
+	return s.getKeyPrefix() + s.KeyDelimiter + suffix, nil
 }
 
 func (s *redisStore) getScoreSetKey(key string) string {
-	return s.KeyPrefix + s.ScoreKeyDelimiter + key
+	return s.getKeyPrefix() + s.ScoreKeyDelimiter + key
 }
 
 func (s *redisStore) getScoreSetKeysKeyByKey(key string) string {
 	return key + s.KeyDelimiter + s.ScoreSetKeysKeySuffix
 }
 
-func (s *redisStore) toModel(rv reflect.Value) (Model, error) {
-	if rv.Type() != s.modelType && rv.Type().Elem() != s.modelType {
-		return nil, fmt.Errorf("%s is not a %v", rv.Interface(), s.modelType)
+func (s *redisStore) getLexSetMembersKeyByKey(key string) string {
+	return key + s.KeyDelimiter + s.LexSetMembersKeySuffix
+}
+
+// lexMemberDelimiter separates a value and a key in members of lex sets.
+// Keys should not contain it.
+const lexMemberDelimiter = "\x00"
+
+func getLexMember(value, key string) string {
+	return value + lexMemberDelimiter + key
+}
+
+// getKeyByLexMember returns a key in a member of a lex set, or the member itself when it is a member of a score set.
+func getKeyByLexMember(member string) string {
+	return member[strings.LastIndex(member, lexMemberDelimiter)+len(lexMemberDelimiter):]
+}
+
+var lexIndexerType = reflect.TypeOf((*LexIndexer)(nil)).Elem()
+
+// isLexIndexed reports whether models in the store are indexed in lex sets.
+func (s *redisStore) isLexIndexed() bool {
+	return reflect.PtrTo(s.modelType).Implements(lexIndexerType)
+}
+
+// storeKeyDelimiter joins a key prefix and names of keys recording states of the whole store.
+// Keys of models and score sets are joined with KeyDelimiter and ScoreKeyDelimiter, so they never collide with these keys.
+const storeKeyDelimiter = "#"
+
+func (s *redisStore) getStoreKey(name string) string {
+	return s.getKeyPrefix() + storeKeyDelimiter + name
+}
+
+func (s *redisStore) getExpirationsKey() string {
+	return s.getStoreKey(s.ExpirationsKeySuffix)
+}
+
+var expirerType = reflect.TypeOf((*Expirer)(nil)).Elem()
+
+// isExpirable reports whether models in the store can expire.
+func (s *redisStore) isExpirable() bool {
+	return s.TTL > 0 || reflect.PtrTo(s.modelType).Implements(expirerType)
+}
+
+func (s *redisStore) getTTL(m interface{}) time.Duration {
+	if e, ok := m.(Expirer); ok {
+		return e.GetTTL()
 	}
+	return s.TTL
+}
 
-	m, ok := rv.Interface().(Model)
-	if !ok {
-		return nil, fmt.Errorf("failed to cast %v to ro.IModel", rv.Interface())
+func (s *redisStore) toModel(rv reflect.Value) (interface{}, error) {
+	if indirectType(rv.Type()) != s.modelType {
+		return nil, fmt.Errorf("%s is not a %v", rv.Interface(), s.modelType)
 	}
 
-	if len(m.GetKeySuffix()) == 0 {
-		return nil, fmt.Errorf("%v.GetKeySuffix() should be present", m)
+	var m interface{}
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() {
+		m = rv.Addr().Interface()
+	} else {
+		m = rv.Interface()
 	}
 
 	return m, nil
 }
 
+func (s *redisStore) toModels(rv reflect.Value) ([]interface{}, error) {
+	if rv.Kind() != reflect.Slice {
+		m, err := s.toModel(rv)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to convert to model")
+		}
+		return []interface{}{m}, nil
+	}
+
+	models := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		m, err := s.toModel(rv.Index(i))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to convert to model")
+		}
+		models[i] = m
+	}
+	return models, nil
+}
+
 func (s *redisStore) selectKeys(ctx context.Context, mods []rq.Modifier) ([]string, error) {
-	conn, err := s.pool.GetContext(ctx)
+	conn, err := s.getConn(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to acquire a connection")
 	}
 	defer conn.Close()
 
-	cmd, err := s.injectKeyPrefix(rq.List(mods...)).Build()
+	q, err := s.prepareQuery(rq.List(mods...))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	keys, err := redis.Strings(conn.Do(cmd.Name, cmd.Args...))
+	keys, err := redis.Strings(s.doQuery(conn, q))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
+	for i, m := range keys {
+		keys[i] = getKeyByLexMember(m)
+	}
+
 	return keys, nil
 }
 
+// prepareQuery fills store specific parameters of the query.
+func (s *redisStore) prepareQuery(q *rq.Query) (*rq.Query, error) {
+	if q.SetOp != nil {
+		// set operations are stored into a temporary key, which is removed after the query
+		if len(q.Key.Tokens) > 0 {
+			return nil, errors.New("a key cannot be specified with a set operation")
+		}
+		q.Key = s.newTmpKey()
+	}
+	s.injectKeyPrefix(q)
+	s.adjustLexRange(q)
+	err := s.checkQuerySlots(q)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return q, nil
+}
+
 func (s *redisStore) injectKeyPrefix(q *rq.Query) *rq.Query {
 	if q.Key.Prefix == "" {
-		q.Key.Prefix = s.KeyPrefix
+		q.Key.Prefix = s.getKeyPrefix()
+	}
+	if q.SetOp != nil {
+		for i := range q.SetOp.Keys {
+			if q.SetOp.Keys[i].Prefix == "" {
+				q.SetOp.Keys[i].Prefix = s.getKeyPrefix()
+			}
+		}
 	}
 	return q
 }
+
+// newTmpKey returns a unique key, which is used as a destination of a set operation.
+// It is joined with storeKeyDelimiter, so that it is not taken as a score set by Verify.
+func (s *redisStore) newTmpKey() rq.QueryKey {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return rq.QueryKey{
+		Prefix:          s.getKeyPrefix(),
+		PrefixDelimiter: storeKeyDelimiter,
+		Tokens:          []interface{}{"tmp", hex.EncodeToString(b)},
+	}
+}
+
+// doQuery executes a command built from the query, and returns its reply.
+func (s *redisStore) doQuery(conn Conn, q *rq.Query) (interface{}, error) {
+	cmd, err := q.Build()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if q.SetOp != nil {
+		reply, err := s.doWithSetOp(conn, q, func() error {
+			return conn.Send(cmd.Name, cmd.Args...)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "faild to execute %v", cmd)
+		}
+		return reply, nil
+	}
+
+	reply, err := conn.Do(cmd.Name, cmd.Args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "faild to execute %v", cmd)
+	}
+	return reply, nil
+}
+
+// doWithSetOp stores a set operation of the query into its key, and sends a command with send in a transaction.
+// The key is removed in the same transaction, so no temporary sorted sets are left.
+func (s *redisStore) doWithSetOp(conn Conn, q *rq.Query, send func() error) (interface{}, error) {
+	storeCmd, err := q.BuildSetOp()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dest := storeCmd.Args[0]
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	err = conn.Send(storeCmd.Name, storeCmd.Args...)
+	if err == nil {
+		err = send()
+	}
+	if err == nil {
+		err = conn.Send("DEL", dest)
+	}
+	if err != nil {
+		conn.Do("DISCARD")
+		return nil, errors.Wrap(err, "faild to send any commands")
+	}
+
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, errors.Wrap(err, "faild to EXEC commands")
+	}
+	for _, r := range replies {
+		if err, ok := r.(error); ok {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return replies[1], nil
+}
+
+// adjustLexRange converts bounds of values into bounds of lex set members, which are formed as `<value><delimiter><key>`.
+// An exclusive lower bound and an inclusive upper bound should cover all members with the bound value.
+func (s *redisStore) adjustLexRange(q *rq.Query) {
+	if strings.HasPrefix(q.LexMin, "(") {
+		q.LexMin = "(" + q.LexMin[1:] + lexMemberDelimiter + rq.LexMaxByte
+	}
+	if strings.HasPrefix(q.LexMax, "[") {
+		q.LexMax = "[" + q.LexMax[1:] + lexMemberDelimiter + rq.LexMaxByte
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,503 @@
+package ro
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// VerifyOptions specifies how Verify scans stored models and indexes.
+type VerifyOptions struct {
+	// BatchSize is the number of keys or members requested by a SCAN or a ZSCAN, and they are verified in a transaction (default: 100).
+	BatchSize int
+	// Interval is a pause between batches, which throttles Verify against live instances.
+	Interval time.Duration
+	// Repair fixes found inconsistencies in the same transactions as verifying them.
+	Repair bool
+}
+
+// IndexMember is a membership of a model in a score set or a lex set.
+type IndexMember struct {
+	SetKey string
+	Key    string
+}
+
+// VerifyReport contains inconsistencies found by Verify.
+// Models waiting for Sweep are not reported, since Sweep removes their memberships and bookkeeping keys.
+type VerifyReport struct {
+	// OrphanedMembers are members of score sets and lex sets whose models are missing.
+	OrphanedMembers []IndexMember
+	// MissingMembers are memberships produced by stored models but missing from sets or bookkeeping keys, or having other scores.
+	MissingMembers []IndexMember
+	// StaleMembers are memberships recorded for stored models but no longer produced by them, which Reindex also removes.
+	StaleMembers []IndexMember
+	// DanglingScoreSetKeys are memberships recorded for stored models but missing from sets.
+	DanglingScoreSetKeys []IndexMember
+	// DanglingKeys are bookkeeping keys of missing models.
+	DanglingKeys []string
+}
+
+// OK reports whether no inconsistencies are found.
+func (r *VerifyReport) OK() bool {
+	return len(r.OrphanedMembers) == 0 && len(r.MissingMembers) == 0 && len(r.StaleMembers) == 0 &&
+		len(r.DanglingScoreSetKeys) == 0 && len(r.DanglingKeys) == 0
+}
+
+func (r *VerifyReport) merge(other *VerifyReport) {
+	r.OrphanedMembers = append(r.OrphanedMembers, other.OrphanedMembers...)
+	r.MissingMembers = append(r.MissingMembers, other.MissingMembers...)
+	r.StaleMembers = append(r.StaleMembers, other.StaleMembers...)
+	r.DanglingScoreSetKeys = append(r.DanglingScoreSetKeys, other.DanglingScoreSetKeys...)
+	r.DanglingKeys = append(r.DanglingKeys, other.DanglingKeys...)
+}
+
+func (r *VerifyReport) sort() {
+	for _, members := range [][]IndexMember{r.OrphanedMembers, r.MissingMembers, r.StaleMembers, r.DanglingScoreSetKeys} {
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].SetKey != members[j].SetKey {
+				return members[i].SetKey < members[j].SetKey
+			}
+			return members[i].Key < members[j].Key
+		})
+	}
+	sort.Strings(r.DanglingKeys)
+}
+
+// Verify implements the types.Store interface.
+func (s *redisStore) Verify(ctx context.Context, opts VerifyOptions) (*VerifyReport, error) {
+	report, err := s.intercept(ctx, &Operation{Name: "Verify"}, func(ctx context.Context, op *Operation) (interface{}, error) {
+		return s.doVerify(ctx, opts)
+	})
+	r, _ := report.(*VerifyReport)
+	return r, err
+}
+
+// doVerify scans stored models with their bookkeeping keys, and then score sets and lex sets.
+// Each batch is verified under WATCH, so inconsistencies made by concurrent writes are verified again by a retry.
+func (s *redisStore) doVerify(ctx context.Context, opts VerifyOptions) (*VerifyReport, error) {
+	if !s.HashStoreEnabled {
+		return nil, errors.New("Verify requires the hash store")
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = reindexBatchSize
+	}
+
+	conn, err := s.getConn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to acquire a connection")
+	}
+	defer conn.Close()
+
+	report := &VerifyReport{}
+
+	pattern := escapeGlob(s.getKeyPrefix()+s.KeyDelimiter) + "*"
+	err = scanAll(ctx, conn, pattern, batchSize, opts.Interval, func(keys []string) error {
+		var bookkeepingKeys []string
+		for _, k := range keys {
+			if _, ok := s.getKeyByBookkeepingKey(k); ok {
+				bookkeepingKeys = append(bookkeepingKeys, k)
+			}
+		}
+		modelKeys := s.rejectBookkeepingKeys(keys)
+
+		err := s.retryVerify(report, func() (*VerifyReport, error) {
+			return s.verifyModels(conn, modelKeys, opts.Repair)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify %v", modelKeys)
+		}
+		err = s.retryVerify(report, func() (*VerifyReport, error) {
+			return s.verifyBookkeepingKeys(conn, bookkeepingKeys, opts.Repair)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify %v", bookkeepingKeys)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pattern = escapeGlob(s.getKeyPrefix()+s.ScoreKeyDelimiter) + "*"
+	err = scanAll(ctx, conn, pattern, batchSize, opts.Interval, func(setKeys []string) error {
+		for _, setKey := range setKeys {
+			err := iterateCursor(ctx, opts.Interval, func(cursor string) (string, error) {
+				next, members, err := scanMembers(conn, setKey, cursor, batchSize)
+				if err != nil {
+					return "", errors.WithStack(err)
+				}
+				err = s.retryVerify(report, func() (*VerifyReport, error) {
+					return s.verifyMembers(conn, setKey, members, opts.Repair)
+				})
+				if err != nil {
+					return "", errors.Wrapf(err, "failed to verify members of %s", setKey)
+				}
+				return next, nil
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	report.sort()
+	return report, nil
+}
+
+// retryVerify verifies a batch with verify until its transaction succeeds, and merges the result into the report.
+func (s *redisStore) retryVerify(report *VerifyReport, verify func() (*VerifyReport, error)) error {
+	var (
+		r   *VerifyReport
+		err error
+	)
+	for i := 0; i < txRetryLimit; i++ {
+		r, err = verify()
+		if errors.Cause(err) != errTxAborted {
+			break
+		}
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	report.merge(r)
+	return nil
+}
+
+// scanMembers executes ZSCAN, and returns the next cursor and members.
+func scanMembers(conn Conn, setKey, cursor string, count int) (string, []string, error) {
+	reply, err := redis.Values(conn.Do("ZSCAN", setKey, cursor, "COUNT", count))
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "faild to execute ZSCAN %s %s", setKey, cursor)
+	}
+	if len(reply) != 2 {
+		return "", nil, errors.Errorf("unexpected ZSCAN reply %v", reply)
+	}
+	next, err := redis.String(reply[0], nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "faild to cast a cursor of ZSCAN")
+	}
+	pairs, err := redis.Strings(reply[1], nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "faild to cast members of ZSCAN")
+	}
+	members := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		members = append(members, pairs[i])
+	}
+	return next, members, nil
+}
+
+// getKeyByBookkeepingKey returns a key of a model from a key of its score set keys set or lex set members hash.
+func (s *redisStore) getKeyByBookkeepingKey(k string) (string, bool) {
+	for _, suffix := range []string{s.ScoreSetKeysKeySuffix, s.LexSetMembersKeySuffix} {
+		if strings.HasSuffix(k, s.KeyDelimiter+suffix) {
+			return strings.TrimSuffix(k, s.KeyDelimiter+suffix), true
+		}
+	}
+	return "", false
+}
+
+// membershipCheck is a membership of a model compared with a member of a set.
+type membershipCheck struct {
+	entry    *putEntry
+	setKey   string
+	member   string
+	score    string
+	produced bool
+	recorded bool
+}
+
+// verifyModels compares memberships produced by models stored with keys with memberships in sets and bookkeeping keys.
+// Memberships of models with inconsistencies are rebuilt on repair.
+func (s *redisStore) verifyModels(conn Conn, keys []string, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	if len(keys) == 0 {
+		return report, nil
+	}
+
+	err := s.watchModels(conn, keys)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	entries, err := s.createReindexEntries(conn, keys)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.WithStack(err)
+	}
+
+	zsetKeysByKey, lexMembersByKey, err := s.readIndexes(conn, entries)
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.WithStack(err)
+	}
+
+	var checks []*membershipCheck
+	for _, e := range entries {
+		recorded := make(map[string]bool)
+		for _, zk := range zsetKeysByKey[s.getScoreSetKeysKeyByKey(e.key)] {
+			recorded[zk] = true
+		}
+		for zk, score := range e.scores {
+			checks = append(checks, &membershipCheck{entry: e, setKey: zk, member: e.key, score: fmt.Sprint(score), produced: true, recorded: recorded[zk]})
+		}
+		for zk := range recorded {
+			if _, ok := e.scores[zk]; !ok {
+				checks = append(checks, &membershipCheck{entry: e, setKey: zk, member: e.key, recorded: true})
+			}
+		}
+
+		recordedLex := lexMembersByKey[s.getLexSetMembersKeyByKey(e.key)]
+		for zk, member := range e.lexMembers {
+			checks = append(checks, &membershipCheck{entry: e, setKey: zk, member: member, produced: true, recorded: recordedLex[zk] == member})
+		}
+		for zk, member := range recordedLex {
+			if e.lexMembers[zk] != member {
+				checks = append(checks, &membershipCheck{entry: e, setKey: zk, member: member, recorded: true})
+			}
+		}
+	}
+
+	for _, c := range checks {
+		err = conn.Send("ZSCORE", c.setKey, c.member)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to send ZSCORE %s %q", c.setKey, c.member)
+		}
+	}
+	err = conn.Flush()
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.Wrap(err, "faild to flush ZSCORE commands")
+	}
+
+	var inconsistent []*putEntry
+	for _, c := range checks {
+		score, err := redis.Float64(conn.Receive())
+		if err != nil && err != redis.ErrNil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to execute ZSCORE %s %q", c.setKey, c.member)
+		}
+		exists := err == nil
+
+		m := IndexMember{SetKey: c.setKey, Key: c.entry.key}
+		switch {
+		case c.produced && (!exists || !c.recorded || c.score != "" && !sameScore(score, c.score)):
+			report.MissingMembers = append(report.MissingMembers, m)
+		case !c.produced && exists:
+			report.StaleMembers = append(report.StaleMembers, m)
+		case !c.produced:
+			report.DanglingScoreSetKeys = append(report.DanglingScoreSetKeys, m)
+		default:
+			continue
+		}
+		if len(inconsistent) == 0 || inconsistent[len(inconsistent)-1] != c.entry {
+			inconsistent = append(inconsistent, c.entry)
+		}
+	}
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	if repair {
+		err = s.sendIndexes(conn, inconsistent, zsetKeysByKey, lexMembersByKey)
+		if err != nil {
+			conn.Do("DISCARD")
+			return nil, errors.Wrap(err, "faild to send any commands")
+		}
+	}
+
+	err = execVerification(conn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return report, nil
+}
+
+// sameScore reports whether a score read from a sorted set is the score produced by a model.
+func sameScore(stored float64, produced string) bool {
+	f, err := strconv.ParseFloat(produced, 64)
+	return err == nil && f == stored
+}
+
+// verifyBookkeepingKeys finds bookkeeping keys of missing models, and removes them on repair.
+func (s *redisStore) verifyBookkeepingKeys(conn Conn, keys []string, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	if len(keys) == 0 {
+		return report, nil
+	}
+
+	modelKeys := make([]string, len(keys))
+	for i, k := range keys {
+		modelKeys[i], _ = s.getKeyByBookkeepingKey(k)
+	}
+
+	missing, err := s.watchMissingModels(conn, modelKeys)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for i, k := range keys {
+		if missing[i] {
+			report.DanglingKeys = append(report.DanglingKeys, k)
+		}
+	}
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	if repair && len(report.DanglingKeys) > 0 {
+		err = conn.Send("DEL", redis.Args{}.AddFlat(report.DanglingKeys)...)
+		if err != nil {
+			conn.Do("DISCARD")
+			return nil, errors.Wrapf(err, "failed to send DEL %v", report.DanglingKeys)
+		}
+	}
+
+	err = execVerification(conn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return report, nil
+}
+
+// verifyMembers finds members of the set whose models are missing, and removes them on repair.
+func (s *redisStore) verifyMembers(conn Conn, setKey string, members []string, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	if len(members) == 0 {
+		return report, nil
+	}
+
+	modelKeys := make([]string, len(members))
+	for i, m := range members {
+		modelKeys[i] = getKeyByLexMember(m)
+	}
+
+	missing, err := s.watchMissingModels(conn, modelKeys)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// members may be removed after they were scanned
+	for _, m := range members {
+		err = conn.Send("ZSCORE", setKey, m)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to send ZSCORE %s %q", setKey, m)
+		}
+	}
+	err = conn.Flush()
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.Wrap(err, "faild to flush ZSCORE commands")
+	}
+
+	var orphaned []string
+	for i, m := range members {
+		_, err := redis.Float64(conn.Receive())
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to execute ZSCORE %s %q", setKey, m)
+		}
+		if missing[i] {
+			orphaned = append(orphaned, m)
+			report.OrphanedMembers = append(report.OrphanedMembers, IndexMember{SetKey: setKey, Key: modelKeys[i]})
+		}
+	}
+
+	err = conn.Send("MULTI")
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.Wrap(err, "faild to send MULTI command")
+	}
+
+	if repair && len(orphaned) > 0 {
+		err = conn.Send("ZREM", redis.Args{}.Add(setKey).AddFlat(orphaned)...)
+		if err != nil {
+			conn.Do("DISCARD")
+			return nil, errors.Wrapf(err, "failed to send ZREM %s %v", setKey, orphaned)
+		}
+	}
+
+	err = execVerification(conn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return report, nil
+}
+
+// watchMissingModels watches models stored with keys, and reports whether each of them is missing and not waiting for Sweep.
+// The caller should finish the transaction with MULTI and EXEC, or UNWATCH on failure.
+func (s *redisStore) watchMissingModels(conn Conn, keys []string) ([]bool, error) {
+	_, err := conn.Do("WATCH", redis.Args{}.AddFlat(keys)...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to execute WATCH %v", keys)
+	}
+
+	expirationsKey := s.getExpirationsKey()
+	for _, k := range keys {
+		err = conn.Send("EXISTS", k)
+		if err == nil {
+			err = conn.Send("ZSCORE", expirationsKey, k)
+		}
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to send commands checking %s", k)
+		}
+	}
+	err = conn.Flush()
+	if err != nil {
+		conn.Do("UNWATCH")
+		return nil, errors.Wrap(err, "faild to flush commands checking models")
+	}
+
+	missing := make([]bool, len(keys))
+	for i, k := range keys {
+		exists, err := redis.Bool(conn.Receive())
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to execute EXISTS %s", k)
+		}
+		expiring, err := conn.Receive()
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, errors.Wrapf(err, "failed to execute ZSCORE %s %s", expirationsKey, k)
+		}
+		missing[i] = !exists && expiring == nil
+	}
+	return missing, nil
+}
+
+// execVerification executes a transaction of a verification.
+// It succeeds only when watched keys are not modified, so inconsistencies found under WATCH are not caused by concurrent writes.
+func execVerification(conn Conn) error {
+	reply, err := conn.Do("EXEC")
+	if err != nil {
+		return errors.Wrap(err, "faild to EXEC commands")
+	}
+	if reply == nil {
+		return errTxAborted
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package ro_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/izumin5210/ro"
+	"github.com/izumin5210/ro/rq"
+	rotesting "github.com/izumin5210/ro/testing"
+)
+
+func TestRedisStore_Verify(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &rotesting.Post{})
+	err := store.Put(context.TODO(), []*rotesting.Post{
+		{ID: 1, UpdatedAt: 100},
+		{ID: 2, UpdatedAt: 200},
+		{ID: 3, UpdatedAt: 300},
+		{ID: 4, UpdatedAt: 400},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	for _, cmd := range [][]interface{}{
+		{"DEL", "Post:1"},
+		{"ZADD", "Post/recent", 500, "Post:5"},
+		{"ZREM", "Post/recent", "Post:2"},
+		{"ZADD", "Post/id", 30, "Post:3"},
+		{"SADD", "Post:3:scoreSetKeys", "Post/old"},
+		{"ZADD", "Post/old", 1, "Post:4"},
+		{"SADD", "Post:4:scoreSetKeys", "Post/old"},
+	} {
+		_, err = conn.Do(cmd[0].(string), cmd[1:]...)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	want := &ro.VerifyReport{
+		OrphanedMembers: []ro.IndexMember{
+			{SetKey: "Post/id", Key: "Post:1"},
+			{SetKey: "Post/recent", Key: "Post:1"},
+			{SetKey: "Post/recent", Key: "Post:5"},
+		},
+		MissingMembers: []ro.IndexMember{
+			{SetKey: "Post/id", Key: "Post:3"},
+			{SetKey: "Post/recent", Key: "Post:2"},
+		},
+		StaleMembers: []ro.IndexMember{
+			{SetKey: "Post/old", Key: "Post:4"},
+		},
+		DanglingScoreSetKeys: []ro.IndexMember{
+			{SetKey: "Post/old", Key: "Post:3"},
+		},
+		DanglingKeys: []string{"Post:1:scoreSetKeys"},
+	}
+
+	got, err := store.Verify(context.TODO(), ro.VerifyOptions{BatchSize: 2, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Verify() returned %+v, want %+v", got, want)
+	}
+	if got.OK() {
+		t.Error("VerifyReport.OK() should be false")
+	}
+
+	got, err = store.Verify(context.TODO(), ro.VerifyOptions{Repair: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Verify() with Repair returned %+v, want %+v", got, want)
+	}
+
+	got, err = store.Verify(context.TODO(), ro.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !got.OK() {
+		t.Errorf("Verify() after repairing returned %+v", got)
+	}
+
+	posts := []*rotesting.Post{}
+	err = store.List(context.TODO(), &posts, rq.Key("recent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := len(posts), 3; got != want {
+		t.Errorf("List() returned %d posts, want %d posts", got, want)
+	}
+
+	exists, err := redis.Bool(conn.Do("EXISTS", "Post/old", "Post:1:scoreSetKeys"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exists {
+		t.Error("Stale memberships and dangling keys should be removed")
+	}
+}
+
+func TestRedisStore_Verify_WithLex(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.New(pool, &Author{})
+	err := store.Put(context.TODO(), []*Author{{ID: 1, Name: "bob"}, {ID: 2, Name: "alice"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("ZREM", "Author/name", "bob\x00Author:1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = conn.Do("ZADD", "Author/name", 0, "carol\x00Author:3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := store.Verify(context.TODO(), ro.VerifyOptions{Repair: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &ro.VerifyReport{
+		OrphanedMembers: []ro.IndexMember{{SetKey: "Author/name", Key: "Author:3"}},
+		MissingMembers:  []ro.IndexMember{{SetKey: "Author/name", Key: "Author:1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Verify() returned %+v, want %+v", got, want)
+	}
+
+	authors := []*Author{}
+	err = store.List(context.TODO(), &authors, rq.Key("name"), rq.LexGtEq("b"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []*Author{{ID: 1, Name: "bob"}}; !reflect.DeepEqual(authors, want) {
+		t.Errorf("List() returned %v, want %v", authors, want)
+	}
+}
+
+func TestTypedStore_Verify_WithTTL(t *testing.T) {
+	defer teardown(t)
+
+	store := ro.NewTyped[*rotesting.Post](pool, ro.WithTTL(time.Hour))
+	err := store.Put(context.TODO(), &rotesting.Post{ID: 1, UpdatedAt: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	// the model expired but is not swept yet
+	_, err = conn.Do("DEL", "Post:1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := store.Verify(context.TODO(), ro.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !got.OK() {
+		t.Errorf("Verify() reported models waiting for Sweep: %+v", got)
+	}
+}